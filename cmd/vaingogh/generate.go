@@ -0,0 +1,127 @@
+package main
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+
+	"go.stevenxie.me/vaingogh/config"
+	"go.stevenxie.me/vaingogh/export"
+	"go.stevenxie.me/vaingogh/repo"
+	repogh "go.stevenxie.me/vaingogh/repo/github"
+)
+
+var (
+	generateCmd = &cobra.Command{
+		Use:          "generate",
+		Short:        "Generate a static site of vanity import pages.",
+		RunE:         execGenerate,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	generateOpts struct {
+		Out      string
+		Depth    int
+		NotFound bool
+		CNAME    bool
+	}
+)
+
+func init() {
+	flags := generateCmd.Flags()
+	flags.StringVarP(
+		&generateOpts.Out,
+		"out", "o",
+		"",
+		"The directory to write pages to.",
+	)
+	flags.IntVar(
+		&generateOpts.Depth,
+		"depth",
+		0,
+		"The number of levels of packages below each module to generate "+
+			"pages for.",
+	)
+	flags.BoolVar(
+		&generateOpts.NotFound,
+		"not-found",
+		true,
+		"Generate a catch-all 404.html page that works for every package.",
+	)
+	flags.BoolVar(
+		&generateOpts.CNAME,
+		"cname",
+		false,
+		"Generate a CNAME file containing the host of the base URL.",
+	)
+	if err := generateCmd.MarkFlagRequired("out"); err != nil {
+		panic(err)
+	}
+}
+
+func execGenerate(*cobra.Command, []string) error {
+	// Load and validate config file.
+	cfg, err := config.Load()
+	if err != nil {
+		return errors.Wrap(err, "loading config")
+	}
+	if err = cfg.Validate(); err != nil {
+		return errors.Wrap(err, "invalid config")
+	}
+	if generateOpts.Depth < 0 {
+		return errors.New("depth must not be negative")
+	}
+
+	// Initiate GitHub client.
+	ghclient, err := repogh.NewClient()
+	if err != nil {
+		return errors.Wrap(err, "creating GitHub client")
+	}
+
+	// Build repo services.
+	var lister repo.ListerService
+	{
+		cfg := cfg.Lister
+		lister = repogh.NewLister(
+			ghclient,
+			cfg.GitHub.Username,
+			func(lc *repogh.ListerConfig) {
+				lc.Concurrency = cfg.Concurrency
+			},
+		)
+	}
+	overrides := make(map[string]map[string]repo.Layout)
+	for name, mod := range cfg.Modules {
+		if len(mod.Majors) > 0 {
+			overrides[lister.DeriveRepoFullName(name)] = mod.Majors
+		}
+	}
+	layouts := repo.NewLayoutOverrider(
+		repogh.NewLayoutDetector(ghclient),
+		overrides,
+	)
+
+	// Build page generator.
+	generator, _, err := buildGenerator(cfg, lister)
+	if err != nil {
+		return errors.Wrap(err, "building generator")
+	}
+
+	// Export pages.
+	exporter := export.New(
+		lister, generator,
+		cfg.Server.BaseURL,
+		func(ec *export.Config) {
+			ec.Logger = log
+			ec.Layouts = layouts
+			ec.Packages = repogh.NewPackageLister(ghclient)
+			ec.Depth = generateOpts.Depth
+			ec.NotFound = generateOpts.NotFound
+			ec.CNAME = generateOpts.CNAME
+		},
+	)
+	if _, err = exporter.Export(generateOpts.Out); err != nil {
+		return errors.Wrap(err, "exporting pages")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/cockroachdb/errors"
+
+	"go.stevenxie.me/vaingogh/config"
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/template"
+	tplgh "go.stevenxie.me/vaingogh/template/github"
+)
+
+// A templateFile is a template file that overrides a built-in page template.
+type templateFile struct {
+	path      string
+	templator *template.Templator
+}
+
+// buildGenerator builds the page generator described by cfg, and loads the
+// template files that it uses.
+func buildGenerator(
+	cfg *config.Config,
+	lister repo.ListerService,
+) (template.Generator, []templateFile, error) {
+	var templateFiles []templateFile
+	loadTemplate := func(path string, sample interface{}) (
+		*template.Templator, error) {
+		if path == "" {
+			return nil, nil
+		}
+		tplr, err := template.LoadTemplator(path, sample)
+		if err != nil {
+			return nil, err
+		}
+		templateFiles = append(templateFiles, templateFile{
+			path:      path,
+			templator: tplr,
+		})
+		return tplr, nil
+	}
+
+	// Load template files, if configured.
+	var goGet, landing, index, notFound, errorPage *template.Templator
+	{
+		cfg := cfg.Generator.Templates
+		files := []struct {
+			path      string
+			sample    interface{}
+			templator **template.Templator
+		}{
+			{cfg.GoGet, template.SampleData, &goGet},
+			{cfg.Landing, template.SampleData, &landing},
+			{cfg.Index, template.SampleIndexData, &index},
+			{cfg.NotFound, template.SampleIndexData, &notFound},
+			{cfg.Error, template.SampleErrorData, &errorPage},
+		}
+		for _, file := range files {
+			var err error
+			if *file.templator, err = loadTemplate(
+				file.path,
+				file.sample,
+			); err != nil {
+				return nil, nil, errors.Wrap(err, "loading template")
+			}
+		}
+	}
+
+	// Build per-module overrides.
+	overrides := make(template.Overrides)
+	for name, mod := range cfg.Modules {
+		var (
+			override = mod.Override()
+			err      error
+		)
+		if override.Templator, err = loadTemplate(
+			mod.Template,
+			template.SampleData,
+		); err != nil {
+			return nil, nil, errors.Wrapf(err,
+				"loading template for module '%s'", name)
+		}
+		if override.LandingTemplator, err = loadTemplate(
+			mod.LandingTemplate,
+			template.SampleData,
+		); err != nil {
+			return nil, nil, errors.Wrapf(err,
+				"loading landing template for module '%s'", name)
+		}
+		overrides[lister.DeriveRepoFullName(name)] = override
+	}
+
+	var (
+		baseURL  = cfg.Server.BaseURL
+		modProxy = template.ModProxy{
+			URL:  cfg.ModProxyURL(),
+			Mode: cfg.Generator.ModProxy.Mode,
+		}
+	)
+	gcfg := cfg.Generator
+	generator, err := tplgh.NewGenerator(
+		func(gc *tplgh.GeneratorConfig) {
+			gc.DocsURL = gcfg.Docs.URL
+			gc.MetaRefresh = gcfg.Docs.MetaRefresh
+			gc.Overrides = overrides
+			gc.ModProxy = modProxy
+			gc.Site = template.Site{
+				Name:    gcfg.Site.Name,
+				LogoURL: gcfg.Site.LogoURL,
+				BaseURL: baseURL,
+			}
+			gc.Templator = goGet
+			gc.LandingTemplator = landing
+			gc.IndexTemplator = index
+			gc.NotFoundTemplator = notFound
+			gc.ErrorTemplator = errorPage
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return generator, templateFiles, nil
+}
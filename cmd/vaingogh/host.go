@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v27/github"
+	"github.com/sirupsen/logrus"
+
+	"go.stevenxie.me/vaingogh/config"
+	"go.stevenxie.me/vaingogh/metrics"
+	"go.stevenxie.me/vaingogh/pkg/urlutil"
+	"go.stevenxie.me/vaingogh/repo"
+	repogh "go.stevenxie.me/vaingogh/repo/github"
+	"go.stevenxie.me/vaingogh/repo/webhook"
+	"go.stevenxie.me/vaingogh/template"
+)
+
+// hostServices are the services used to serve a single vanity domain.
+type hostServices struct {
+	baseURL       string
+	lister        repo.ListerService
+	layouts       repo.LayoutService
+	watcher       *repo.Watcher
+	generator     template.Generator
+	templateFiles []templateFile
+}
+
+// hostConfigs returns the configs of the additional hosts in cfg.
+func hostConfigs(cfg *config.Config) []*config.Config {
+	names := cfg.HostNames()
+	cfgs := make([]*config.Config, len(names))
+	for i, name := range names {
+		cfgs[i] = cfg.ForHost(name)
+	}
+	return cfgs
+}
+
+// buildHostServices builds the services used to serve the vanity domain at
+// cfg.Server.BaseURL.
+//
+// The returned watcher must be stopped once it is no longer needed.
+func buildHostServices(
+	cfg *config.Config,
+	ghclient *github.Client,
+	mets *metrics.Metrics,
+) (*hostServices, error) {
+	hs := hostServices{baseURL: cfg.Server.BaseURL}
+
+	// Create lister using GitHub client.
+	{
+		cfg := cfg.Lister
+		hs.lister = repogh.NewLister(
+			ghclient,
+			cfg.GitHub.Username,
+			func(lc *repogh.ListerConfig) {
+				lc.Concurrency = cfg.Concurrency
+			},
+		)
+	}
+
+	// Build layout service, which detects module layouts using the GitHub
+	// client unless they are configured explicitly.
+	{
+		overrides := make(map[string]map[string]repo.Layout)
+		for name, mod := range cfg.Modules {
+			if len(mod.Majors) > 0 {
+				overrides[hs.lister.DeriveRepoFullName(name)] = mod.Majors
+			}
+		}
+		hs.layouts = repo.NewLayoutOverrider(
+			repogh.NewLayoutDetector(
+				ghclient,
+				func(ldc *repogh.LayoutDetectorConfig) {
+					ldc.TTL = cfg.Watcher.CheckInterval
+				},
+			),
+			overrides,
+		)
+	}
+
+	// Build page generator.
+	var err error
+	if hs.generator, hs.templateFiles, err = buildGenerator(
+		cfg,
+		hs.lister,
+	); err != nil {
+		return nil, errors.Wrap(err, "building generator")
+	}
+	if mets != nil {
+		hs.generator = mets.InstrumentGenerator(hs.generator)
+	}
+
+	// Build repo watcher.
+	{
+		source := "github/" + cfg.Lister.GitHub.Username
+		lister := hs.lister
+		if mets != nil {
+			lister = mets.InstrumentLister(source, lister)
+		}
+
+		cfg := cfg.Watcher
+		hs.watcher = repo.NewWatcher(
+			lister,
+			cfg.CheckInterval,
+			func(wc *repo.WatcherConfig) {
+				wc.Logger = log.WithFields(logrus.Fields{
+					"component": "repo.Watcher",
+					"baseURL":   hs.baseURL,
+				})
+				wc.Source = source
+			},
+		)
+
+		// Notify webhooks of repo list changes, identifying the host and
+		// source that they belong to.
+		host := urlutil.StripProtocol(hs.baseURL)
+		if i := strings.IndexByte(host, '/'); i > -1 {
+			host = host[:i]
+		}
+		for _, url := range cfg.Webhooks {
+			var (
+				notifier = webhook.NewNotifier(url, func(nc *webhook.NotifierConfig) {
+					nc.Host = host
+					nc.Source = source
+				})
+				log = log.WithFields(logrus.Fields{
+					"component": "webhook.Notifier",
+					"url":       url,
+				})
+			)
+			hs.watcher.Subscribe(func(event repo.Event) {
+				go func() {
+					if err := notifier.Notify(event); err != nil {
+						log.WithError(err).Error("Failed to notify webhook.")
+					}
+				}()
+			})
+		}
+	}
+	return &hs, nil
+}
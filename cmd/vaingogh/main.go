@@ -22,6 +22,7 @@ var (
 func init() {
 	app.AddCommand(reposCmd)
 	app.AddCommand(serveCmd)
+	app.AddCommand(generateCmd)
 	app.AddCommand(completionCmd)
 
 	// Disable help command.
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/spf13/cobra"
@@ -52,9 +54,31 @@ func execRepos(*cobra.Command, []string) error {
 		return err
 	}
 
-	// Print repos, one on each line.
+	// Print repos, one on each line, followed by the versions of each of
+	// their modules.
 	for _, repo := range repos {
 		fmt.Println(repo)
+		for _, mv := range repo.Modules {
+			printModuleVersions(mv)
+		}
 	}
 	return nil
 }
+
+func printModuleVersions(mv *repo.ModuleVersions) {
+	name := path.Join(mv.Dir, mv.Major)
+	if name == "" {
+		name = "."
+	}
+	latest := mv.Latest
+	if latest == "" {
+		latest = "(none)"
+	} else if mv.Prerelease {
+		latest += " (prerelease)"
+	}
+	fmt.Printf("  %s: %s, %d version(s)", name, latest, len(mv.Versions))
+	if len(mv.Retracted) > 0 {
+		fmt.Printf(", retracted: %s", strings.Join(mv.Retracted, ", "))
+	}
+	fmt.Println()
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/cockroachdb/errors"
@@ -14,12 +18,14 @@ import (
 
 	"go.stevenxie.me/api/pkg/cmdutil"
 	"go.stevenxie.me/vaingogh/config"
+	"go.stevenxie.me/vaingogh/internal/info"
+	"go.stevenxie.me/vaingogh/metrics"
+	"go.stevenxie.me/vaingogh/proxy"
 	"go.stevenxie.me/vaingogh/server"
 
 	"go.stevenxie.me/vaingogh/repo"
 	repogh "go.stevenxie.me/vaingogh/repo/github"
 	"go.stevenxie.me/vaingogh/template"
-	tplgh "go.stevenxie.me/vaingogh/template/github"
 )
 
 var (
@@ -41,11 +47,11 @@ func init() {
 		&serveOpts.Port,
 		"port", "p",
 		3000,
-		"The port to listen on.",
+		"The port to listen on, unless server.listen is configured.",
 	)
 }
 
-func execServe(*cobra.Command, []string) error {
+func execServe(cmd *cobra.Command, _ []string) error {
 	// Load and validate config file.
 	cfg, err := config.Load()
 	if err != nil {
@@ -54,6 +60,9 @@ func execServe(*cobra.Command, []string) error {
 	if err = cfg.Validate(); err != nil {
 		return errors.Wrap(err, "invalid config")
 	}
+	if cfg.ListenConfigured() && cmd.Flags().Changed("port") {
+		return errors.New("--port cannot be used with server.listen")
+	}
 
 	// Finalizers should be run before the program terminates.
 	var finalizers cmdutil.Finalizers
@@ -74,72 +83,231 @@ func execServe(*cobra.Command, []string) error {
 		}
 	}()
 
-	// Build repo service.
-	var lister repo.ListerService
-	{
-		// Initiate GitHub client.
-		client, err := repogh.NewClient()
-		if err != nil {
-			return errors.Wrap(err, "creating GitHub client")
-		}
+	// Collect metrics, if enabled.
+	var mets *metrics.Metrics
+	if cfg.Server.Metrics.Enabled {
+		mets = metrics.New()
+	}
 
-		// Create lister using GitHub client.
-		cfg := cfg.Lister
-		lister = repogh.NewLister(
-			client,
-			cfg.GitHub.Username,
-			func(lc *repogh.ListerConfig) {
-				lc.Concurrency = cfg.Concurrency
-			},
-		)
+	// Initiate GitHub client.
+	ghclient, err := repogh.NewClient(func(cc *repogh.ClientConfig) {
+		if mets != nil {
+			cc.WrapTransport = mets.InstrumentGitHubTransport
+		}
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating GitHub client")
 	}
 
-	// Build validator service.
-	var validator repo.ValidatorService
-	{
-		var (
-			cfg     = cfg.Watcher
-			watcher = repo.NewWatcher(
-				lister,
-				cfg.CheckInterval,
-				func(wc *repo.WatcherConfig) {
-					wc.Logger = log.WithField("component", "repo.Watcher")
-				},
-			)
-		)
-		validator = watcher
+	// Build the services of the primary host, and of each additional host.
+	var hosts []*hostServices
+	for _, cfg := range append(
+		[]*config.Config{cfg},
+		hostConfigs(cfg)...,
+	) {
+		hs, err := buildHostServices(cfg, ghclient, mets)
+		if err != nil {
+			return errors.Wrapf(err, "building services for '%s'",
+				cfg.Server.BaseURL)
+		}
+		hosts = append(hosts, hs)
+
+		watcher := hs.watcher
 		finalizers = append(finalizers, func() error {
 			watcher.Stop()
 			return nil
 		})
 	}
 
-	// Build page generator.
-	var generator template.Generator
-	{
-		if generator, err = tplgh.NewGenerator(); err != nil {
-			return errors.Wrap(err, "building generator")
+	// Refresh the repos of every host together upon a hangup signal (the
+	// admin endpoint only refreshes the repos of the requested host).
+	refreshers := make([]repo.RefresherService, len(hosts))
+	for i, hs := range hosts {
+		refreshers[i] = hs.watcher
+	}
+	refresher := repo.JoinRefreshers(refreshers...)
+
+	// Build access log, which is written to stdout separately from
+	// application logs.
+	var accessLog *server.AccessLogConfig
+	if cfg := cfg.Server.AccessLog; cfg.Enabled {
+		proxies, err := server.ParseTrustedProxies(cfg.TrustedProxies)
+		if err != nil {
+			return errors.Wrap(err, "parsing trusted proxies")
+		}
+
+		logger := logrus.New()
+		logger.SetOutput(os.Stdout)
+		if cfg.Format == "json" {
+			logger.SetFormatter(new(logrus.JSONFormatter))
+		} else {
+			logger.SetFormatter(&logrus.TextFormatter{
+				DisableColors: true,
+				FullTimestamp: true,
+			})
+		}
+		accessLog = &server.AccessLogConfig{
+			Logger:         logger,
+			SampleRate:     cfg.SampleRate,
+			TrustedProxies: proxies,
+		}
+	}
+
+	// Build TLS config, if HTTPS is enabled.
+	var tlsConfig *server.TLSConfig
+	if cfg.TLSEnabled() {
+		cfg := cfg.Server.TLS
+		tlsConfig = &server.TLSConfig{
+			CertFile:       cfg.CertFile,
+			KeyFile:        cfg.KeyFile,
+			ReloadInterval: cfg.ReloadInterval,
+			RedirectAddr:   cfg.RedirectAddr,
+		}
+		if acfg := cfg.ACME; acfg.Enabled {
+			tlsConfig.ACME = &server.ACMEConfig{
+				DirectoryURL: acfg.DirectoryURL,
+				Email:        acfg.Email,
+				CacheDir:     acfg.CacheDir,
+			}
+			if acfg.RootCAFile != "" {
+				pem, err := ioutil.ReadFile(acfg.RootCAFile)
+				if err != nil {
+					return errors.Wrap(err, "reading root CA file")
+				}
+				roots := x509.NewCertPool()
+				if !roots.AppendCertsFromPEM(pem) {
+					return errors.New("no certificates found in root CA file")
+				}
+				tlsConfig.ACME.RootCAs = roots
+			}
 		}
 	}
 
 	// Build and run server.
 	var srv *server.Server
 	{
+		var (
+			pcfg     = cfg.Proxy
+			interval = cfg.Watcher.CheckInterval
+		)
 		cfg := cfg.Server
+		adminToken := cfg.AdminToken
+		if adminToken == "" {
+			adminToken = os.Getenv(strings.ToUpper(info.Namespace) + "_ADMIN_TOKEN")
+		}
+		primary := hosts[0]
 		if srv, err = server.New(
-			generator, validator,
+			primary.generator, primary.watcher,
 			cfg.BaseURL,
-			func(c *server.Config) { c.Logger = log },
+			func(c *server.Config) {
+				c.Logger = log
+				c.Layouts = primary.layouts
+				c.Snapshots = primary.watcher
+				c.AdminToken = adminToken
+
+				c.Sources = []repo.StatusService{primary.watcher}
+				c.Refresher = primary.watcher
+
+				// Configure health endpoints; by default, listings become stale
+				// after several missed checks.
+				c.LivenessPath = cfg.Health.LivenessPath
+				c.ReadinessPath = cfg.Health.ReadinessPath
+				c.MaxStaleness = cfg.Health.MaxStaleness
+				if c.MaxStaleness == 0 {
+					c.MaxStaleness = 3 * interval
+				}
+
+				// Configure metrics.
+				c.Metrics = mets
+				c.MetricsPath = cfg.Metrics.Path
+				c.AdminAddr = cfg.Metrics.AdminAddr
+
+				// Configure access log.
+				c.AccessLog = accessLog
+
+				// Configure TLS.
+				c.TLS = tlsConfig
+
+				// Configure additional hosts, and page caching.
+				for _, hs := range hosts[1:] {
+					host := server.Host{
+						BaseURL:   hs.baseURL,
+						Generator: hs.generator,
+						Validator: hs.watcher,
+						Layouts:   hs.layouts,
+						Snapshots: hs.watcher,
+						Sources:   []repo.StatusService{hs.watcher},
+						Refresher: hs.watcher,
+					}
+					if cfg.Cache.Enabled {
+						host.Events = hs.watcher
+					}
+					c.Hosts = append(c.Hosts, host)
+				}
+				if cfg.Cache.Enabled {
+					c.Events = primary.watcher
+				}
+				c.CacheMaxAge = cfg.Cache.MaxAge
+
+				// Configure module proxy.
+				c.ProxyPath = pcfg.Path
+				c.ProxyOptions = append(c.ProxyOptions, func(pc *proxy.Config) {
+					if pcfg.CacheDir != "" {
+						pc.CacheDir = pcfg.CacheDir
+					}
+					pc.FetchInterval = pcfg.FetchInterval
+				})
+			},
 		); err != nil {
 			return errors.Wrap(err, "creating server")
 		}
 	}
 
+	// Reload template files when they change, clearing cached pages that were
+	// generated from the previous templates.
+	var templateFiles []templateFile
+	for _, hs := range hosts {
+		templateFiles = append(templateFiles, hs.templateFiles...)
+	}
+	if interval := cfg.Generator.Templates.ReloadInterval; (interval > 0) &&
+		(len(templateFiles) > 0) {
+		reloader := template.NewReloader(
+			interval,
+			func(rc *template.ReloaderConfig) {
+				rc.Logger = log.WithField("component", "template.Reloader")
+				rc.OnReload = srv.ClearCache
+			},
+		)
+		for _, file := range templateFiles {
+			if err = reloader.Watch(file.path, file.templator); err != nil {
+				reloader.Stop()
+				return errors.Wrap(err, "watching template file")
+			}
+		}
+		finalizers = append(finalizers, func() error {
+			reloader.Stop()
+			return nil
+		})
+	}
+
 	// Shut down server gracefully upon interrupt.
 	go shutdownServerUponInterrupt(srv, log, cfg.Server.ShutdownTimeout)
 
-	// Start server on the specified port.
-	err = srv.ListenAndServe(fmt.Sprintf(":%d", serveOpts.Port))
+	// Refresh repos upon hangup.
+	go refreshReposUponHangup(refresher, log)
+
+	// Start server on the configured listener, or on the specified port.
+	lc := &server.ListenConfig{Addr: fmt.Sprintf(":%d", serveOpts.Port)}
+	if cfg.ListenConfigured() {
+		if lc, err = cfg.ListenConfig(); err != nil {
+			return errors.Wrap(err, "invalid listen config")
+		}
+	}
+	ln, err := server.Listen(*lc)
+	if err != nil {
+		return errors.Wrap(err, "listening")
+	}
+	err = srv.Serve(ln)
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return errors.Wrap(err, "starting server")
 	}
@@ -151,7 +319,7 @@ func shutdownServerUponInterrupt(
 	log *logrus.Logger,
 	timeout *time.Duration,
 ) {
-	sig := make(chan os.Signal)
+	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 
 	// Wait for interrupt signal.
@@ -176,3 +344,27 @@ func shutdownServerUponInterrupt(
 		log.WithError(err).Error("Server didn't shut down correctly.")
 	}
 }
+
+func refreshReposUponHangup(
+	refresher repo.RefresherService,
+	log *logrus.Logger,
+) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	for range sig {
+		log.Info("Received hangup signal; refreshing repos.")
+		res, err := refresher.Refresh()
+		if err != nil {
+			log.WithError(err).Error("Failed to refresh repos.")
+			continue
+		}
+		log.WithFields(logrus.Fields{
+			"numRepos": res.NumRepos,
+			"added":    res.Diff.Added,
+			"removed":  res.Diff.Removed,
+			"changed":  res.Diff.Changed,
+			"duration": res.Duration.String(),
+		}).Info("Refreshed repos.")
+	}
+}
@@ -1,9 +1,19 @@
 package config
 
 import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/errors"
+
+	"go.stevenxie.me/vaingogh/pkg/urlutil"
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/server"
+	"go.stevenxie.me/vaingogh/server/resolve"
+	"go.stevenxie.me/vaingogh/template"
 )
 
 // Config is used to configure vaingogh.
@@ -11,10 +21,65 @@ type Config struct {
 	Server struct {
 		BaseURL         string         `yaml:"baseURL"`
 		ShutdownTimeout *time.Duration `yaml:"shutdownTimeout"`
+		AdminToken      string         `yaml:"adminToken"`
+
+		// Listen configures where the server listens for connections: on a TCP
+		// address, a Unix domain socket, or a socket passed by systemd. If
+		// unset, the server listens on the port given by '--port'.
+		Listen struct {
+			Addr        string `yaml:"addr"`
+			Socket      string `yaml:"socket"`
+			SocketMode  string `yaml:"socketMode"`
+			SocketGroup string `yaml:"socketGroup"`
+			Systemd     bool   `yaml:"systemd"`
+			SystemdName string `yaml:"systemdName"`
+		} `yaml:"listen"`
+
+		Health struct {
+			LivenessPath  string        `yaml:"livenessPath"`
+			ReadinessPath string        `yaml:"readinessPath"`
+			MaxStaleness  time.Duration `yaml:"maxStaleness"`
+		} `yaml:"health"`
+
+		Metrics struct {
+			Enabled   bool   `yaml:"enabled"`
+			Path      string `yaml:"path"`
+			AdminAddr string `yaml:"adminAddr"`
+		} `yaml:"metrics"`
+
+		AccessLog struct {
+			Enabled        bool     `yaml:"enabled"`
+			Format         string   `yaml:"format"`
+			SampleRate     float64  `yaml:"sampleRate"`
+			TrustedProxies []string `yaml:"trustedProxies"`
+		} `yaml:"accessLog"`
+
+		Cache struct {
+			Enabled bool          `yaml:"enabled"`
+			MaxAge  time.Duration `yaml:"maxAge"`
+		} `yaml:"cache"`
+
+		// TLS configures the server to serve HTTPS, using either certificate
+		// files or ACME.
+		TLS struct {
+			CertFile       string        `yaml:"certFile"`
+			KeyFile        string        `yaml:"keyFile"`
+			ReloadInterval time.Duration `yaml:"reloadInterval"`
+			RedirectAddr   string        `yaml:"redirectAddr"`
+
+			ACME struct {
+				Enabled      bool   `yaml:"enabled"`
+				Email        string `yaml:"email"`
+				CacheDir     string `yaml:"cacheDir"`
+				DirectoryURL string `yaml:"directoryURL"`
+				RootCAFile   string `yaml:"rootCAFile"`
+			} `yaml:"acme"`
+		} `yaml:"tls"`
 	} `yaml:"server"`
 
 	Watcher struct {
 		CheckInterval time.Duration `yaml:"checkInterval"`
+		Webhooks      []string      `yaml:"webhooks"`
 	} `yaml:"watcher"`
 
 	Lister struct {
@@ -23,12 +88,146 @@ type Config struct {
 			Username string `yaml:"username"`
 		} `yaml:"github"`
 	} `yaml:"lister"`
+
+	Proxy struct {
+		Path          string        `yaml:"path"`
+		CacheDir      string        `yaml:"cacheDir"`
+		FetchInterval time.Duration `yaml:"fetchInterval"`
+	} `yaml:"proxy"`
+
+	Generator struct {
+		Docs struct {
+			URL         string `yaml:"url"`
+			MetaRefresh bool   `yaml:"metaRefresh"`
+		} `yaml:"docs"`
+
+		// ModProxy configures a module proxy to advertise in 'go-import' meta
+		// tags.
+		ModProxy struct {
+			URL  string `yaml:"url"`
+			Mode string `yaml:"mode"`
+		} `yaml:"modProxy"`
+
+		// Site configures the branding of generated pages.
+		Site struct {
+			Name    string `yaml:"name"`
+			LogoURL string `yaml:"logoURL"`
+		} `yaml:"site"`
+
+		// Templates are paths to template files that override the built-in
+		// page templates.
+		Templates struct {
+			GoGet          string        `yaml:"goGet"`
+			Landing        string        `yaml:"landing"`
+			Index          string        `yaml:"index"`
+			NotFound       string        `yaml:"notFound"`
+			Error          string        `yaml:"error"`
+			ReloadInterval time.Duration `yaml:"reloadInterval"`
+		} `yaml:"templates"`
+	} `yaml:"generator"`
+
+	// Modules configures individual modules, by name.
+	Modules map[string]ModuleConfig `yaml:"modules"`
+
+	// Hosts configures additional vanity domains to serve, by host (i.e.
+	// 'go.example.com').
+	Hosts map[string]HostConfig `yaml:"hosts"`
+}
+
+// HostConfig configures an additional vanity domain. Fields that are left
+// empty default to the corresponding top-level fields.
+type HostConfig struct {
+	// BasePath is the path under the host that modules are served at (i.e.
+	// '/go').
+	BasePath string `yaml:"basePath"`
+
+	Lister struct {
+		GitHub struct {
+			Username string `yaml:"username"`
+		} `yaml:"github"`
+	} `yaml:"lister"`
+
+	Generator struct {
+		Docs struct {
+			URL string `yaml:"url"`
+		} `yaml:"docs"`
+
+		Site struct {
+			Name    string `yaml:"name"`
+			LogoURL string `yaml:"logoURL"`
+		} `yaml:"site"`
+
+		Templates struct {
+			GoGet    string `yaml:"goGet"`
+			Landing  string `yaml:"landing"`
+			Index    string `yaml:"index"`
+			NotFound string `yaml:"notFound"`
+			Error    string `yaml:"error"`
+		} `yaml:"templates"`
+	} `yaml:"generator"`
+
+	// Modules replaces the top-level module configs for the host's modules.
+	Modules map[string]ModuleConfig `yaml:"modules"`
+}
+
+// ModuleConfig configures an individual module.
+type ModuleConfig struct {
+	// Majors maps major version suffixes (i.e. 'v2') to the location of those
+	// major versions within the module's repo. Major versions that aren't
+	// configured are detected automatically, if they have been tagged.
+	Majors map[string]repo.Layout `yaml:"majors"`
+
+	// DocsURL overrides the base URL of the documentation site for the module.
+	DocsURL string `yaml:"docsURL"`
+
+	// VCS and ImportURL override the version control system and repo URL that
+	// the 'go' command fetches the module from.
+	VCS       string `yaml:"vcs"`
+	ImportURL string `yaml:"importURL"`
+
+	// SourceURL, SourceTreeURL, and SourceBlobURL override the URLs that
+	// the module's source is browsed at.
+	SourceURL     string `yaml:"sourceURL"`
+	SourceTreeURL string `yaml:"sourceTreeURL"`
+	SourceBlobURL string `yaml:"sourceBlobURL"`
+
+	// ModProxy overrides generator.modProxy.mode for the module.
+	ModProxy string `yaml:"modProxy"`
+
+	// Template and LandingTemplate are paths to template files that override
+	// the module's 'go get' and landing page templates.
+	Template        string `yaml:"template"`
+	LandingTemplate string `yaml:"landingTemplate"`
+}
+
+// Override returns a template.Override that describes how the module's pages
+// are overridden, excluding its templates.
+func (mc *ModuleConfig) Override() template.Override {
+	return template.Override{
+		DocsURL:       mc.DocsURL,
+		VCSType:       mc.VCS,
+		ImportURL:     mc.ImportURL,
+		SourceURL:     mc.SourceURL,
+		SourceTreeURL: mc.SourceTreeURL,
+		SourceBlobURL: mc.SourceBlobURL,
+		ModProxy:      mc.ModProxy,
+	}
 }
 
 func defaultConfig() *Config {
 	cfg := new(Config)
+	cfg.Server.Health.LivenessPath = server.DefaultLivenessPath
+	cfg.Server.Health.ReadinessPath = server.DefaultReadinessPath
+	cfg.Server.Metrics.Path = server.DefaultMetricsPath
+	cfg.Server.AccessLog.Format = "logfmt"
+	cfg.Server.AccessLog.SampleRate = 1
+	cfg.Server.Cache.Enabled = true
+	cfg.Server.Cache.MaxAge = 5 * time.Minute
 	cfg.Watcher.CheckInterval = time.Hour
 	cfg.Lister.Concurrency = 5
+	cfg.Proxy.FetchInterval = 5 * time.Minute
+	cfg.Generator.Docs.URL = "https://pkg.go.dev"
+	cfg.Generator.Docs.MetaRefresh = true
 	return cfg
 }
 
@@ -51,9 +250,342 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	// Validate proxy config.
+	if (cfg.Proxy.Path != "") && (cfg.Proxy.FetchInterval < 0) {
+		return errors.New("proxy fetch interval must not be negative " +
+			"(proxy.fetchInterval)")
+	}
+
+	// Validate template files.
+	{
+		tpls := &cfg.Generator.Templates
+		files := []struct{ path, key string }{
+			{tpls.GoGet, "goGet"},
+			{tpls.Landing, "landing"},
+			{tpls.Index, "index"},
+			{tpls.NotFound, "notFound"},
+			{tpls.Error, "error"},
+		}
+		for _, file := range files {
+			if err := validateFile(file.path); err != nil {
+				return errors.Wrapf(err, "invalid template file "+
+					"(generator.templates.%s)", file.key)
+			}
+		}
+		if tpls.ReloadInterval < 0 {
+			return errors.New("template reload interval must not be negative " +
+				"(generator.templates.reloadInterval)")
+		}
+	}
+
+	// Validate module proxy config. Its URL defaults to that of the built-in
+	// module proxy, if it is enabled.
+	{
+		mp := template.ModProxy{
+			URL:  cfg.ModProxyURL(),
+			Mode: cfg.Generator.ModProxy.Mode,
+		}
+		if (mp.URL == "") && (cfg.Proxy.Path != "") && (mp.Mode != "") &&
+			(mp.Mode != template.ModProxyOff) {
+			return errors.New("module proxy URL is required, since the " +
+				"scheme of the base URL is unknown; include it in the base URL " +
+				"(i.e. 'https://go.example.com') or set the proxy URL " +
+				"(server.baseURL, generator.modProxy.url)")
+		}
+		if err := mp.Validate(); err != nil {
+			return errors.Wrap(err, "invalid module proxy (generator.modProxy)")
+		}
+		for name, mod := range cfg.Modules {
+			if (mp.URL == "") && (mod.ModProxy != "") &&
+				(mod.ModProxy != template.ModProxyOff) {
+				return errors.Newf("module proxy URL is required "+
+					"(generator.modProxy.url, modules.%s.modProxy)", name)
+			}
+		}
+	}
+
+	// Validate module configs.
+	for name, mod := range cfg.Modules {
+		for major := range mod.Majors {
+			if !resolve.IsMajorSuffix(major) {
+				return errors.Newf("invalid major version suffix '%s' "+
+					"(modules.%s.majors)", major, name)
+			}
+		}
+		override := mod.Override()
+		if err := override.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid override (modules.%s)", name)
+		}
+		if err := validateFile(mod.Template); err != nil {
+			return errors.Wrapf(err, "invalid template file "+
+				"(modules.%s.template)", name)
+		}
+		if err := validateFile(mod.LandingTemplate); err != nil {
+			return errors.Wrapf(err, "invalid template file "+
+				"(modules.%s.landingTemplate)", name)
+		}
+	}
+
 	// Validate server config.
 	if cfg.Server.BaseURL == "" {
 		return errors.New("server base URL must not be empty (server.baseURL)")
 	}
+	if l := &cfg.Server.Listen; cfg.ListenConfigured() {
+		var n int
+		for _, set := range []bool{l.Addr != "", l.Socket != "", l.Systemd} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return errors.New("exactly one of an address, a socket, and " +
+				"systemd must be configured (server.listen)")
+		}
+		if (l.Socket == "") && ((l.SocketMode != "") || (l.SocketGroup != "")) {
+			return errors.New("socket permissions require a socket " +
+				"(server.listen.socket)")
+		}
+		if !l.Systemd && (l.SystemdName != "") {
+			return errors.New("systemd socket names require systemd " +
+				"(server.listen.systemd)")
+		}
+		if _, err := cfg.ListenConfig(); err != nil {
+			return err
+		}
+	}
+	for _, host := range cfg.HostNames() {
+		if (host == "") || strings.ContainsAny(host, "/:") {
+			return errors.Newf("invalid host '%s' (hosts)", host)
+		}
+		if strings.EqualFold(host, hostOf(cfg.Server.BaseURL)) {
+			return errors.Newf("host '%s' is already served at the base URL "+
+				"(hosts.%s, server.baseURL)", host, host)
+		}
+		if err := cfg.ForHost(host).Validate(); err != nil {
+			return errors.Wrapf(err, "invalid host config (hosts.%s)", host)
+		}
+	}
+	{
+		health := &cfg.Server.Health
+		if !strings.HasPrefix(health.LivenessPath, "/") {
+			return errors.New("liveness path must start with '/' " +
+				"(server.health.livenessPath)")
+		}
+		if !strings.HasPrefix(health.ReadinessPath, "/") {
+			return errors.New("readiness path must start with '/' " +
+				"(server.health.readinessPath)")
+		}
+		if health.LivenessPath == health.ReadinessPath {
+			return errors.New("liveness and readiness paths must differ " +
+				"(server.health)")
+		}
+		if health.MaxStaleness < 0 {
+			return errors.New("max staleness must not be negative " +
+				"(server.health.maxStaleness)")
+		}
+	}
+	if m := &cfg.Server.Metrics; m.Enabled && !strings.HasPrefix(m.Path, "/") {
+		return errors.New("metrics path must start with '/' " +
+			"(server.metrics.path)")
+	}
+	if al := &cfg.Server.AccessLog; al.Enabled {
+		switch al.Format {
+		case "json", "logfmt":
+		default:
+			return errors.Newf("unknown access log format '%s' "+
+				"(server.accessLog.format)", al.Format)
+		}
+		if (al.SampleRate <= 0) || (al.SampleRate > 1) {
+			return errors.New("access log sample rate must be in (0, 1] " +
+				"(server.accessLog.sampleRate)")
+		}
+		if _, err := server.ParseTrustedProxies(al.TrustedProxies); err != nil {
+			return errors.Wrap(err, "invalid trusted proxy "+
+				"(server.accessLog.trustedProxies)")
+		}
+	}
+	if cfg.Server.Cache.MaxAge < 0 {
+		return errors.New("cache max age must not be negative " +
+			"(server.cache.maxAge)")
+	}
+	if tls := &cfg.Server.TLS; cfg.TLSEnabled() {
+		if tls.ACME.Enabled {
+			if (tls.CertFile != "") || (tls.KeyFile != "") {
+				return errors.New("certificate files cannot be used with ACME " +
+					"(server.tls.certFile, server.tls.keyFile)")
+			}
+			if err := validateFile(tls.ACME.RootCAFile); err != nil {
+				return errors.Wrap(err, "invalid root CA file "+
+					"(server.tls.acme.rootCAFile)")
+			}
+		} else {
+			if (tls.CertFile == "") || (tls.KeyFile == "") {
+				return errors.New("both a certificate file and a key file are " +
+					"required (server.tls.certFile, server.tls.keyFile)")
+			}
+			if err := validateFile(tls.CertFile); err != nil {
+				return errors.Wrap(err, "invalid certificate file "+
+					"(server.tls.certFile)")
+			}
+			if err := validateFile(tls.KeyFile); err != nil {
+				return errors.Wrap(err, "invalid key file (server.tls.keyFile)")
+			}
+		}
+		if tls.ReloadInterval < 0 {
+			return errors.New("certificate reload interval must not be " +
+				"negative (server.tls.reloadInterval)")
+		}
+	} else if tls.RedirectAddr != "" {
+		return errors.New("HTTPS redirects require TLS to be configured " +
+			"(server.tls.redirectAddr)")
+	}
+	return nil
+}
+
+// ModProxyURL returns the URL of the module proxy to advertise in 'go-import'
+// meta tags, which defaults to that of the built-in module proxy.
+//
+// The built-in module proxy is served at the root of the base URL's host, using
+// the scheme of the base URL. If the base URL has no scheme, HTTPS is assumed
+// only if the server serves HTTPS itself; otherwise, the proxy's URL is
+// unknown, and "" is returned.
+func (cfg *Config) ModProxyURL() string {
+	if url := cfg.Generator.ModProxy.URL; url != "" {
+		return url
+	}
+	if cfg.Proxy.Path == "" {
+		return ""
+	}
+	scheme := schemeOf(cfg.Server.BaseURL)
+	if scheme == "" {
+		if !cfg.TLSEnabled() {
+			return ""
+		}
+		scheme = "https"
+	}
+	return scheme + "://" + hostOf(cfg.Server.BaseURL) + "/" +
+		strings.Trim(cfg.Proxy.Path, "/")
+}
+
+// ListenConfigured returns true if the server is configured with where to
+// listen for connections.
+func (cfg *Config) ListenConfigured() bool {
+	l := &cfg.Server.Listen
+	return (l.Addr != "") || (l.Socket != "") || (l.SocketMode != "") ||
+		(l.SocketGroup != "") || l.Systemd || (l.SystemdName != "")
+}
+
+// ListenConfig returns the server.ListenConfig described by
+// cfg.Server.Listen.
+func (cfg *Config) ListenConfig() (*server.ListenConfig, error) {
+	l := &cfg.Server.Listen
+	lc := server.ListenConfig{
+		Addr:        l.Addr,
+		Socket:      l.Socket,
+		SocketGroup: l.SocketGroup,
+		Systemd:     l.Systemd,
+		SystemdName: l.SystemdName,
+	}
+	if l.SocketMode != "" {
+		mode, err := strconv.ParseUint(l.SocketMode, 8, 32)
+		if (err != nil) || (mode > 0777) {
+			return nil, errors.Newf("invalid socket mode '%s', expected octal "+
+				"permissions like '0660' (server.listen.socketMode)",
+				l.SocketMode)
+		}
+		lc.SocketMode = os.FileMode(mode)
+	}
+	return &lc, nil
+}
+
+// TLSEnabled returns true if the server is configured to serve HTTPS.
+func (cfg *Config) TLSEnabled() bool {
+	tls := &cfg.Server.TLS
+	return (tls.CertFile != "") || (tls.KeyFile != "") || tls.ACME.Enabled
+}
+
+// HostNames returns the names of the additional hosts to serve, in sorted
+// order.
+func (cfg *Config) HostNames() []string {
+	names := make([]string, 0, len(cfg.Hosts))
+	for name := range cfg.Hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForHost returns the config for serving the additional host named host,
+// which is cfg with the host's fields applied.
+func (cfg *Config) ForHost(host string) *Config {
+	hc := cfg.Hosts[host]
+	derived := *cfg
+	derived.Hosts = nil
+	derived.Server.BaseURL = host
+	if path := strings.Trim(hc.BasePath, "/"); path != "" {
+		derived.Server.BaseURL += "/" + path
+	}
+	if scheme := schemeOf(cfg.Server.BaseURL); scheme != "" {
+		derived.Server.BaseURL = scheme + "://" + derived.Server.BaseURL
+	}
+
+	if username := hc.Lister.GitHub.Username; username != "" {
+		derived.Lister.GitHub.Username = username
+	}
+	if hc.Modules != nil {
+		derived.Modules = hc.Modules
+	}
+
+	var (
+		gen  = &derived.Generator
+		hgen = &hc.Generator
+	)
+	setIfEmpty := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	setIfEmpty(&gen.Docs.URL, hgen.Docs.URL)
+	setIfEmpty(&gen.Site.Name, hgen.Site.Name)
+	setIfEmpty(&gen.Site.LogoURL, hgen.Site.LogoURL)
+	setIfEmpty(&gen.Templates.GoGet, hgen.Templates.GoGet)
+	setIfEmpty(&gen.Templates.Landing, hgen.Templates.Landing)
+	setIfEmpty(&gen.Templates.Index, hgen.Templates.Index)
+	setIfEmpty(&gen.Templates.NotFound, hgen.Templates.NotFound)
+	setIfEmpty(&gen.Templates.Error, hgen.Templates.Error)
+	return &derived
+}
+
+// hostOf returns the host of a base URL, i.e. 'example.com'.
+// schemeOf returns the scheme of baseURL (i.e. 'https'), or "" if it has
+// none.
+func schemeOf(baseURL string) string {
+	if i := strings.Index(baseURL, "://"); i > 0 {
+		return strings.ToLower(baseURL[:i])
+	}
+	return ""
+}
+
+func hostOf(baseURL string) string {
+	baseURL = urlutil.StripProtocol(baseURL)
+	if i := strings.IndexByte(baseURL, '/'); i > -1 {
+		return baseURL[:i]
+	}
+	return baseURL
+}
+
+// validateFile returns an error if path is non-empty, and doesn't refer to a
+// readable file.
+func validateFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.Newf("'%s' is a directory", path)
+	}
 	return nil
 }
@@ -0,0 +1,61 @@
+package config
+
+import "testing"
+
+func TestModProxyURL(t *testing.T) {
+	cases := []struct {
+		name    string
+		baseURL string
+		tls     bool
+		url     string // generator.modProxy.url
+		want    string
+	}{
+		{
+			name:    "explicit",
+			baseURL: "go.example.com",
+			url:     "https://athens.example.com",
+			want:    "https://athens.example.com",
+		},
+		{
+			name:    "scheme from base URL",
+			baseURL: "http://go.example.com",
+			want:    "http://go.example.com/proxy",
+		},
+		{
+			name:    "base path",
+			baseURL: "https://example.com/go/",
+			want:    "https://example.com/proxy",
+		},
+		{
+			name:    "TLS",
+			baseURL: "example.com/go",
+			tls:     true,
+			want:    "https://example.com/proxy",
+		},
+		{
+			name:    "unknown scheme",
+			baseURL: "go.example.com",
+			want:    "",
+		},
+	}
+	for _, c := range cases {
+		cfg := defaultConfig()
+		cfg.Server.BaseURL = c.baseURL
+		cfg.Server.TLS.ACME.Enabled = c.tls
+		cfg.Proxy.Path = "/proxy/"
+		cfg.Generator.ModProxy.URL = c.url
+		if got := cfg.ModProxyURL(); got != c.want {
+			t.Errorf("%s: expected %q, got %q", c.name, c.want, got)
+		}
+	}
+
+	// Additional hosts inherit the base URL's scheme.
+	cfg := defaultConfig()
+	cfg.Server.BaseURL = "http://go.example.com"
+	cfg.Proxy.Path = "/proxy"
+	cfg.Hosts = map[string]HostConfig{"go.example.org": {BasePath: "/x"}}
+	if got := cfg.ForHost("go.example.org").ModProxyURL(); got !=
+		"http://go.example.org/proxy" {
+		t.Errorf("host: expected 'http://go.example.org/proxy', got %q", got)
+	}
+}
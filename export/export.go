@@ -0,0 +1,278 @@
+package export
+
+import (
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"go.stevenxie.me/api/pkg/zero"
+
+	"go.stevenxie.me/vaingogh/pkg/urlutil"
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/server/resolve"
+	"go.stevenxie.me/vaingogh/template"
+)
+
+// New creates a new Exporter, which exports pages for the repos listed by
+// lister, served under baseURL.
+func New(
+	lister repo.ListerService,
+	gen template.Generator,
+	baseURL string,
+	opts ...func(*Config),
+) *Exporter {
+	cfg := Config{
+		Logger: zero.Logger(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Exporter{
+		lister:    lister,
+		generator: gen,
+		baseURL:   strings.Trim(urlutil.StripProtocol(baseURL), "/"),
+		layouts:   cfg.Layouts,
+		packages:  cfg.Packages,
+		depth:     cfg.Depth,
+		notFound:  cfg.NotFound,
+		cname:     cfg.CNAME,
+		log:       cfg.Logger,
+	}
+}
+
+type (
+	// An Exporter exports vanity import pages as a static site, for hosting on
+	// services like GitHub Pages or S3.
+	//
+	// Each module is exported as '<module>/index.html', which is the same
+	// landing page that a Server would serve (and which contains the module's
+	// 'go-import' meta tags).
+	Exporter struct {
+		lister    repo.ListerService
+		generator template.Generator
+		baseURL   string
+		layouts   repo.LayoutService
+		packages  repo.PackageService
+		depth     int
+		notFound  bool
+		cname     bool
+		log       logrus.FieldLogger
+	}
+
+	// A Config configures an Exporter.
+	Config struct {
+		// Layouts is used to locate modules within their repos.
+		Layouts repo.LayoutService
+
+		// Packages is used to list the packages within each repo, so that pages
+		// can be exported for them. It is required if Depth is positive.
+		Packages repo.PackageService
+
+		// Depth is the number of levels of packages below each module root to
+		// export pages for. If zero, pages are only exported for module roots.
+		Depth int
+
+		// NotFound determines whether or not a catch-all '404.html' page is
+		// exported, which contains 'go-import' meta tags for every module (so
+		// that packages without their own pages can still be fetched).
+		NotFound bool
+
+		// CNAME determines whether or not a 'CNAME' file containing the host of
+		// the base URL is exported, for GitHub Pages.
+		CNAME bool
+
+		Logger logrus.FieldLogger
+	}
+
+	// A Result summarizes an export.
+	Result struct {
+		Written   int // the number of files that were created or changed
+		Unchanged int // the number of files that were already up-to-date
+		Removed   int // the number of stale files that were removed
+		Skipped   int // the number of pages skipped for unknown modules
+	}
+)
+
+// Export exports pages into dir.
+//
+// Export is idempotent: files that are already up-to-date are left untouched,
+// and files from previous exports that are no longer needed (i.e. the pages
+// of removed modules) are removed. Other files in dir are left alone.
+//
+// Pages whose import paths don't belong to a known module (i.e. the
+// directories of major versions without any matching tags) are skipped.
+func (e *Exporter) Export(dir string) (*Result, error) {
+	if (e.depth > 0) && (e.packages == nil) {
+		return nil, errors.New("export: a PackageService is required to " +
+			"export packages")
+	}
+
+	repos, err := e.lister.ListGoRepos()
+	if err != nil {
+		return nil, errors.Wrap(err, "export: listing Go repos")
+	}
+	var (
+		resolver = resolve.NewResolver(e.baseURL, snapshotValidator{
+			ListerService: e.lister,
+			snap:          repo.NewSnapshot(repos, nil),
+		})
+		host, basePath = splitHost(e.baseURL)
+		site           = newSite(dir)
+	)
+
+	// Export module pages.
+	for _, r := range repos {
+		paths, err := e.importPaths(resolver, r)
+		if err != nil {
+			return nil, err
+		}
+		for _, importPath := range paths {
+			err := e.exportPage(site, resolver, importPath)
+			if errors.Is(err, resolve.ErrNotFound) {
+				// The path doesn't belong to a known module (i.e. it's the
+				// directory of a major version without any matching tags), so
+				// skip it rather than failing the whole export.
+				e.log.
+					WithError(err).
+					WithField("importPath", importPath).
+					Warn("Skipping page for unknown module.")
+				site.result.Skipped++
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Export index page, and catch-all page.
+	imps := resolver.Imports(repos)
+	html, err := e.generator.GenerateIndexHTML(e.baseURL, imps)
+	if err != nil {
+		return nil, errors.Wrap(err, "export: generating index page")
+	}
+	if err = site.write(path.Join(basePath, "index.html"), html); err != nil {
+		return nil, err
+	}
+	if e.notFound {
+		html, err := e.generator.GenerateNotFoundHTML(e.baseURL, imps)
+		if err != nil {
+			return nil, errors.Wrap(err, "export: generating not found page")
+		}
+		if err = site.write("404.html", html); err != nil {
+			return nil, err
+		}
+	}
+	if e.cname {
+		if err = site.write("CNAME", host+"\n"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove files left over from previous exports.
+	if err = site.prune(); err != nil {
+		return nil, err
+	}
+	e.log.WithFields(logrus.Fields{
+		"written":   site.result.Written,
+		"unchanged": site.result.Unchanged,
+		"removed":   site.result.Removed,
+		"skipped":   site.result.Skipped,
+	}).Info("Exported pages.")
+	return &site.result, nil
+}
+
+// exportPage exports the page for importPath.
+//
+// If importPath doesn't belong to a known module, an error wrapping
+// resolve.ErrNotFound is returned.
+func (e *Exporter) exportPage(
+	site *site,
+	resolver *resolve.Resolver,
+	importPath string,
+) error {
+	res, err := resolver.Resolve(importPath)
+	if err != nil {
+		return errors.Wrapf(err, "export: resolving '%s'", importPath)
+	}
+	imp, err := res.Import(e.layouts)
+	if err != nil {
+		return errors.Wrapf(err, "export: locating '%s'", importPath)
+	}
+	html, err := e.generator.GenerateLandingHTML(imp)
+	if err != nil {
+		return errors.Wrapf(err, "export: generating page for '%s'",
+			importPath)
+	}
+	_, rel := splitHost(res.ImportPath)
+	return site.write(path.Join(rel, "index.html"), html)
+}
+
+// importPaths returns the import paths within r to export pages for.
+func (e *Exporter) importPaths(
+	resolver *resolve.Resolver,
+	r *repo.Repo,
+) ([]string, error) {
+	var (
+		root  = resolver.Root(e.lister.DerivePartialName(r.Name))
+		paths = []string{root}
+		seen  = map[string]bool{root: true}
+	)
+	add := func(importPath string) {
+		if !seen[importPath] {
+			seen[importPath] = true
+			paths = append(paths, importPath)
+		}
+	}
+
+	// Export the roots of major versions.
+	for _, mv := range r.Modules {
+		if (mv.Dir == "") && (mv.Major != "") {
+			add(root + "/" + mv.Major)
+		}
+	}
+
+	// Export packages, up to the configured depth.
+	if e.depth > 0 {
+		pkgs, err := e.packages.ListPackages(r.Name, r.DefaultBranch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "export: listing packages of '%s'",
+				r.Name)
+		}
+		for _, pkg := range pkgs {
+			if (pkg != "") && (strings.Count(pkg, "/") < e.depth) {
+				add(root + "/" + pkg)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// splitHost splits an import path into its host, and the path that follows
+// it.
+func splitHost(importPath string) (host, rest string) {
+	if i := strings.IndexByte(importPath, '/'); i > -1 {
+		return importPath[:i], importPath[i+1:]
+	}
+	return importPath, ""
+}
+
+// A snapshotValidator is a repo.ValidatorService backed by a single
+// repo.Snapshot.
+type snapshotValidator struct {
+	repo.ListerService
+	snap *repo.Snapshot
+}
+
+var _ repo.ValidatorService = snapshotValidator{}
+
+func (sv snapshotValidator) IsRepoValid(name string) (bool, error) {
+	return sv.snap.Contains(name), nil
+}
+
+func (sv snapshotValidator) LookupRepo(name string) (*repo.Repo, bool,
+	error) {
+	r, ok := sv.snap.Lookup(name)
+	return r, ok, nil
+}
@@ -0,0 +1,164 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/template"
+)
+
+type (
+	// testLister lists a fixed set of repos owned by 'user'.
+	testLister []*repo.Repo
+
+	// testLayouts locates every module at the root of its repo, but only
+	// knows of the major versions in majors.
+	testLayouts struct{ majors map[string]bool }
+
+	// testGenerator generates pages that only contain their import paths.
+	testGenerator struct{ template.Generator }
+)
+
+var (
+	_ repo.ListerService = testLister{}
+	_ repo.LayoutService = testLayouts{}
+)
+
+func (tl testLister) ListGoRepos() ([]*repo.Repo, error) { return tl, nil }
+
+func (testLister) DeriveRepoFullName(partial string) string {
+	return "user/" + partial
+}
+
+func (testLister) DerivePartialName(name string) string {
+	return strings.TrimPrefix(name, "user/")
+}
+
+func (tl testLayouts) ModuleLayout(r *repo.Repo, major string) (*repo.Layout,
+	error) {
+	if (major != "") && !tl.majors[major] {
+		return nil, repo.ErrUnknownMajor
+	}
+	return new(repo.Layout), nil
+}
+
+func (testGenerator) GenerateLandingHTML(imp template.Import) (string,
+	error) {
+	return imp.Address, nil
+}
+
+func (testGenerator) GenerateIndexHTML(baseURL string,
+	imps []template.Import) (string, error) {
+	return "index of " + baseURL, nil
+}
+
+// listFiles lists the slash-separated paths of the files in dir.
+func listFiles(t *testing.T, dir string) []string {
+	var files []string
+	if err := filepath.Walk(dir, func(name string, info os.FileInfo,
+		err error) error {
+		if (err != nil) || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vaingogh-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		repos = testLister{
+			{Name: "user/a"},
+			{
+				Name: "user/b",
+				Modules: []*repo.ModuleVersions{
+					{Major: "v2", Versions: []string{"v2.0.0"}},
+					{Major: "v3", Versions: []string{"v3.0.0"}}, // unknown layout
+				},
+			},
+		}
+		layouts = testLayouts{majors: map[string]bool{"v2": true}}
+	)
+	export := func(repos testLister) *Result {
+		res, err := New(
+			repos, testGenerator{}, "https://example.com/go",
+			func(cfg *Config) { cfg.Layouts = layouts },
+		).Export(dir)
+		if err != nil {
+			t.Fatalf("exporting: %v", err)
+		}
+		return res
+	}
+
+	// The page of the unknown major version is skipped.
+	res := export(repos)
+	if want := (Result{Written: 4, Skipped: 1}); *res != want {
+		t.Errorf("expected result %+v, got %+v", want, *res)
+	}
+	want := []string{
+		manifestName,
+		"go/a/index.html",
+		"go/b/index.html",
+		"go/b/v2/index.html",
+		"go/index.html",
+	}
+	if files := listFiles(t, dir); strings.Join(files, ",") !=
+		strings.Join(want, ",") {
+		t.Errorf("expected files %v, got %v", want, files)
+	}
+	page, err := ioutil.ReadFile(filepath.Join(dir, "go", "b", "v2",
+		"index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(page) != "example.com/go/b/v2" {
+		t.Errorf("expected page for 'example.com/go/b/v2', got %q", page)
+	}
+
+	// Exporting the same repos again leaves every file untouched.
+	res = export(repos)
+	if want := (Result{Unchanged: 4, Skipped: 1}); *res != want {
+		t.Errorf("re-export: expected result %+v, got %+v", want, *res)
+	}
+
+	// The pages of removed repos are pruned, along with their directories,
+	// but unrelated files are left alone.
+	unrelated := filepath.Join(dir, "go", "b", "notes.txt")
+	if err = ioutil.WriteFile(unrelated, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	res = export(repos[:1])
+	if want := (Result{Unchanged: 2, Removed: 2}); *res != want {
+		t.Errorf("prune: expected result %+v, got %+v", want, *res)
+	}
+	want = []string{
+		manifestName,
+		"go/a/index.html",
+		"go/b/notes.txt",
+		"go/index.html",
+	}
+	if files := listFiles(t, dir); strings.Join(files, ",") !=
+		strings.Join(want, ",") {
+		t.Errorf("prune: expected files %v, got %v", want, files)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "go", "b", "v2")); !os.IsNotExist(
+		err) {
+		t.Errorf("prune: expected empty directory to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// manifestName is the name of the file in which the files written by an
+// export are recorded, so that they can be pruned by later exports.
+const manifestName = ".vaingogh-export"
+
+// A site writes files into an export directory.
+type site struct {
+	dir     string
+	written map[string]bool // the slash-separated paths of written files
+	result  Result
+}
+
+func newSite(dir string) *site {
+	return &site{dir: dir, written: make(map[string]bool)}
+}
+
+// write writes contents to the file at the slash-separated path rel, unless
+// it already contains contents.
+func (s *site) write(rel, contents string) error {
+	if s.written[rel] {
+		return nil
+	}
+	changed, err := writeFile(
+		filepath.Join(s.dir, filepath.FromSlash(rel)),
+		[]byte(contents),
+	)
+	if err != nil {
+		return errors.Wrapf(err, "export: writing '%s'", rel)
+	}
+	s.written[rel] = true
+	if changed {
+		s.result.Written++
+	} else {
+		s.result.Unchanged++
+	}
+	return nil
+}
+
+// prune removes the files that were written by the previous export but not by
+// this one, along with any directories that are left empty, and then records
+// the files that were written by this export.
+func (s *site) prune() error {
+	prev, err := s.readManifest()
+	if err != nil {
+		return err
+	}
+	for _, rel := range prev {
+		if s.written[rel] {
+			continue
+		}
+		name := filepath.Join(s.dir, filepath.FromSlash(rel))
+		if err = os.Remove(name); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return errors.Wrapf(err, "export: removing '%s'", rel)
+		}
+		s.result.Removed++
+		s.removeEmptyParents(name)
+	}
+	return s.writeManifest()
+}
+
+// removeEmptyParents removes the parent directories of name that are empty,
+// up to (but excluding) the export directory.
+func (s *site) removeEmptyParents(name string) {
+	root := filepath.Clean(s.dir)
+	for dir := filepath.Dir(name); (dir != root) &&
+		strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil { // fails if dir isn't empty
+			return
+		}
+	}
+}
+
+func (s *site) readManifest() ([]string, error) {
+	f, err := os.Open(filepath.Join(s.dir, manifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "export: opening manifest")
+	}
+	defer f.Close()
+
+	var (
+		files   []string
+		scanner = bufio.NewScanner(f)
+	)
+	for scanner.Scan() {
+		rel := scanner.Text()
+
+		// Ignore entries that would escape the export directory.
+		if (rel == "") || (path.Clean(rel) != rel) || path.IsAbs(rel) ||
+			(rel == "..") || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		files = append(files, rel)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "export: reading manifest")
+	}
+	return files, nil
+}
+
+func (s *site) writeManifest() error {
+	files := make([]string, 0, len(s.written))
+	for rel := range s.written {
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+
+	var buf bytes.Buffer
+	for _, rel := range files {
+		buf.WriteString(rel)
+		buf.WriteByte('\n')
+	}
+	_, err := writeFile(filepath.Join(s.dir, manifestName), buf.Bytes())
+	return errors.Wrap(err, "export: writing manifest")
+}
+
+// writeFile writes data to the file name (creating its parent directories if
+// necessary), unless it already contains data. It reports whether or not the
+// file was changed.
+//
+// The file is replaced atomically, so that it is never partially written.
+func writeFile(name string, data []byte) (changed bool, err error) {
+	if existing, err := ioutil.ReadFile(name); err == nil &&
+		bytes.Equal(existing, data) {
+		return false, nil
+	}
+
+	dir := filepath.Dir(name)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return false, err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
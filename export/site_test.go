@@ -0,0 +1,76 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSitePruneIgnoresEscapingPaths(t *testing.T) {
+	parent, err := ioutil.TempDir("", "vaingogh-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+
+	// Create files both inside and outside of the export directory, all of
+	// which are listed in a manifest.
+	dir := filepath.Join(parent, "out")
+	for _, name := range []string{
+		filepath.Join(dir, "stale.html"),
+		filepath.Join(dir, "sub", "keep.html"),
+		filepath.Join(parent, "outside.html"),
+		filepath.Join(parent, "abs.html"),
+	} {
+		if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := strings.Join([]string{
+		"stale.html",
+		"../outside.html",
+		"sub/../../outside.html",
+		"./sub/keep.html",
+		"..",
+		filepath.ToSlash(filepath.Join(parent, "abs.html")),
+		"",
+	}, "\n")
+	if err = ioutil.WriteFile(filepath.Join(dir, manifestName),
+		[]byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newSite(dir)
+	if err = s.prune(); err != nil {
+		t.Fatal(err)
+	}
+	if s.result.Removed != 1 {
+		t.Errorf("expected 1 file to be removed, got %d", s.result.Removed)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "stale.html")); !os.IsNotExist(err) {
+		t.Errorf("expected stale file to be removed, got %v", err)
+	}
+	for _, name := range []string{
+		filepath.Join(dir, "sub", "keep.html"), // not in canonical form
+		filepath.Join(parent, "outside.html"),
+		filepath.Join(parent, "abs.html"),
+	} {
+		if _, err = os.Stat(name); err != nil {
+			t.Errorf("expected '%s' to be left alone, got %v", name, err)
+		}
+	}
+
+	// The new manifest lists no files, since none were written.
+	data, err := ioutil.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0 {
+		t.Errorf("expected empty manifest, got %q", data)
+	}
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/template"
+)
+
+// InstrumentLister wraps a repo.ListerService, recording the duration and
+// result of each of its listings under source.
+func (m *Metrics) InstrumentLister(
+	source string,
+	lister repo.ListerService,
+) repo.ListerService {
+	return instrumentedLister{ListerService: lister, source: source, metrics: m}
+}
+
+type instrumentedLister struct {
+	repo.ListerService
+	source  string
+	metrics *Metrics
+}
+
+func (il instrumentedLister) ListGoRepos() ([]*repo.Repo, error) {
+	start := time.Now()
+	repos, err := il.ListerService.ListGoRepos()
+	il.metrics.listingDurations.WithLabelValues(il.source).
+		Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	} else {
+		il.metrics.repos.WithLabelValues(il.source).Set(float64(len(repos)))
+	}
+	il.metrics.listings.WithLabelValues(il.source, result).Inc()
+	return repos, err
+}
+
+// InstrumentGenerator wraps a template.Generator, counting the pages that it
+// fails to render.
+func (m *Metrics) InstrumentGenerator(g template.Generator) template.Generator {
+	return instrumentedGenerator{Generator: g, metrics: m}
+}
+
+type instrumentedGenerator struct {
+	template.Generator
+	metrics *Metrics
+}
+
+func (ig instrumentedGenerator) GenerateHTML(imp template.Import) (
+	html string, err error) {
+	html, err = ig.Generator.GenerateHTML(imp)
+	ig.observe("go-get", err)
+	return html, err
+}
+
+func (ig instrumentedGenerator) WriteHTML(
+	w io.Writer,
+	imp template.Import,
+) error {
+	err := ig.Generator.WriteHTML(w, imp)
+	ig.observe("go-get", err)
+	return err
+}
+
+func (ig instrumentedGenerator) GenerateLandingHTML(imp template.Import) (
+	html string, err error) {
+	html, err = ig.Generator.GenerateLandingHTML(imp)
+	ig.observe("landing", err)
+	return html, err
+}
+
+func (ig instrumentedGenerator) GenerateIndexHTML(
+	baseURL string,
+	imps []template.Import,
+) (html string, err error) {
+	html, err = ig.Generator.GenerateIndexHTML(baseURL, imps)
+	ig.observe("index", err)
+	return html, err
+}
+
+func (ig instrumentedGenerator) GenerateNotFoundHTML(
+	baseURL string,
+	imps []template.Import,
+) (html string, err error) {
+	html, err = ig.Generator.GenerateNotFoundHTML(baseURL, imps)
+	ig.observe("not-found", err)
+	return html, err
+}
+
+func (ig instrumentedGenerator) GenerateErrorHTML(
+	data template.ErrorData,
+) (html string, err error) {
+	html, err = ig.Generator.GenerateErrorHTML(data)
+	ig.observe("error", err)
+	return html, err
+}
+
+func (ig instrumentedGenerator) observe(page string, err error) {
+	if err != nil {
+		ig.metrics.renderErrors.WithLabelValues(page).Inc()
+	}
+}
+
+// InstrumentGitHubTransport wraps the http.RoundTripper of a GitHub API
+// client, counting its requests and recording its remaining rate limit.
+//
+// If rt is nil, http.DefaultTransport is used.
+func (m *Metrics) InstrumentGitHubTransport(
+	rt http.RoundTripper,
+) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return githubTransport{rt: rt, metrics: m}
+}
+
+type githubTransport struct {
+	rt      http.RoundTripper
+	metrics *Metrics
+}
+
+func (gt githubTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	res, err := gt.rt.RoundTrip(r)
+	if err != nil {
+		gt.metrics.githubRequests.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	gt.metrics.githubRequests.WithLabelValues(strconv.Itoa(res.StatusCode)).Inc()
+	if remaining, err := strconv.Atoi(
+		res.Header.Get("X-RateLimit-Remaining"),
+	); err == nil {
+		gt.metrics.githubRateLimit.Set(float64(remaining))
+	}
+	return res, nil
+}
@@ -0,0 +1,142 @@
+// Package metrics exposes Prometheus metrics about a vaingogh server, and
+// the services that it depends on.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace is the namespace of all exported metrics.
+const Namespace = "vaingogh"
+
+// New creates a new Metrics, which registers its collectors (along with the
+// standard Go runtime and process collectors) on a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: "http",
+				Name:      "requests_total",
+				Help:      "Number of HTTP requests handled, by status code and module.",
+			},
+			[]string{"code", "module"},
+		),
+		requestDurations: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: "http",
+				Name:      "request_duration_seconds",
+				Help:      "Latency of HTTP requests, by status code and module.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"code", "module"},
+		),
+
+		listings: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: "watcher",
+				Name:      "refreshes_total",
+				Help:      "Number of repo listings, by source and result.",
+			},
+			[]string{"source", "result"},
+		),
+		listingDurations: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: "watcher",
+				Name:      "refresh_duration_seconds",
+				Help:      "Duration of repo listings, by source.",
+				Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+			},
+			[]string{"source"},
+		),
+		repos: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: "watcher",
+				Name:      "repos",
+				Help:      "Number of Go repos in the latest successful listing, by source.",
+			},
+			[]string{"source"},
+		),
+
+		githubRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: "github",
+				Name:      "api_requests_total",
+				Help:      "Number of GitHub API requests, by status code.",
+			},
+			[]string{"code"},
+		),
+		githubRateLimit: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: "github",
+				Name:      "rate_limit_remaining",
+				Help:      "Number of GitHub API requests remaining in the current rate limit window.",
+			},
+		),
+
+		renderErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: "template",
+				Name:      "render_errors_total",
+				Help:      "Number of failed page renders, by page.",
+			},
+			[]string{"page"},
+		),
+	}
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.requests, m.requestDurations,
+		m.listings, m.listingDurations, m.repos,
+		m.githubRequests, m.githubRateLimit,
+		m.renderErrors,
+	)
+	return m
+}
+
+// Metrics collects metrics about a vaingogh server. It is safe for
+// concurrent use.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	requestDurations *prometheus.HistogramVec
+
+	listings         *prometheus.CounterVec
+	listingDurations *prometheus.HistogramVec
+	repos            *prometheus.GaugeVec
+
+	githubRequests  *prometheus.CounterVec
+	githubRateLimit prometheus.Gauge
+
+	renderErrors *prometheus.CounterVec
+}
+
+// Handler returns an http.Handler that serves metrics in the Prometheus
+// exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records an HTTP request for module (which is empty if the
+// request wasn't for a known module), that was responded to with status after
+// duration d.
+func (m *Metrics) ObserveRequest(status int, module string, d time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(code, module).Inc()
+	m.requestDurations.WithLabelValues(code, module).Observe(d.Seconds())
+}
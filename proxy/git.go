@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	modzip "golang.org/x/mod/zip"
+)
+
+// A gitRepo is a local mirror of a remote git repository. It is safe for
+// concurrent use.
+type gitRepo struct {
+	url string
+	dir string
+
+	mux     sync.RWMutex
+	fetched time.Time
+}
+
+func newGitRepo(url, cacheDir string) *gitRepo {
+	hash := sha256.Sum256([]byte(url))
+	return &gitRepo{
+		url: url,
+		dir: filepath.Join(cacheDir, "git", hex.EncodeToString(hash[:16])),
+	}
+}
+
+// sync clones the remote repo if it hasn't been cloned yet, or fetches
+// updates from it if it hasn't been fetched within interval.
+func (g *gitRepo) sync(interval time.Duration) error {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	if time.Since(g.fetched) < interval {
+		return nil
+	}
+
+	if _, err := os.Stat(g.dir); os.IsNotExist(err) {
+		if err = os.MkdirAll(filepath.Dir(g.dir), 0755); err != nil {
+			return errors.Wrap(err, "creating cache directory")
+		}
+		if _, err = runGit("", "clone", "--mirror", "--quiet", g.url,
+			g.dir); err != nil {
+			os.RemoveAll(g.dir)
+			return errors.Wrap(err, "cloning repo")
+		}
+	} else {
+		if _, err := runGit(g.dir, "remote", "update", "--prune"); err != nil {
+			return errors.Wrap(err, "fetching repo")
+		}
+	}
+	g.fetched = time.Now()
+	return nil
+}
+
+// tags lists the repo's tags.
+func (g *gitRepo) tags() ([]string, error) {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+	out, err := runGit(g.dir, "tag", "--list")
+	if err != nil {
+		return nil, errors.Wrap(err, "listing tags")
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// commitTime returns the commit time of rev.
+func (g *gitRepo) commitTime(rev string) (time.Time, error) {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+	out, err := runGit(g.dir, "log", "-1", "--format=%cI", rev, "--")
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "getting commit time of '%s'", rev)
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(out)))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "parsing commit time")
+	}
+	return t.UTC(), nil
+}
+
+// readFile reads the file at name in rev. If no such file exists, it returns
+// an error that satisfies os.IsNotExist.
+func (g *gitRepo) readFile(rev, name string) ([]byte, error) {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+	obj := rev + ":" + name
+	if _, err := runGit(g.dir, "cat-file", "-e", obj); err != nil {
+		return nil, &os.PathError{Op: "read", Path: obj, Err: os.ErrNotExist}
+	}
+	out, err := runGit(g.dir, "cat-file", "blob", obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading '%s'", obj)
+	}
+	return out, nil
+}
+
+// files returns the files within dir in rev, with paths relative to dir.
+func (g *gitRepo) files(rev, dir string) ([]modzip.File, error) {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+	args := []string{"archive", "--format=tar", rev}
+	if dir != "" {
+		args = append(args, dir)
+	}
+	out, err := runGit(g.dir, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "archiving '%s'", rev)
+	}
+
+	var (
+		files []modzip.File
+		tr    = tar.NewReader(bytes.NewReader(out))
+	)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading archive")
+		}
+		name := hdr.Name
+		if dir != "" {
+			name = strings.TrimPrefix(name, dir+"/")
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir, tar.TypeXGlobalHeader: // git stores the commit ID in a global header
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading '%s' from archive", hdr.Name)
+		}
+		files = append(files, tarFile{
+			path: name,
+			info: hdr.FileInfo(),
+			data: data,
+		})
+	}
+	return files, nil
+}
+
+// tarFile is a modzip.File that was read from a tar archive.
+type tarFile struct {
+	path string
+	info os.FileInfo
+	data []byte
+}
+
+var _ modzip.File = tarFile{}
+
+func (f tarFile) Path() string                { return path.Clean(f.path) }
+func (f tarFile) Lstat() (os.FileInfo, error) { return f.info, nil }
+func (f tarFile) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// runGit runs git with args, using the repo at dir (if dir is non-empty).
+func runGit(dir string, args ...string) ([]byte, error) {
+	if dir != "" {
+		args = append([]string{"--git-dir", dir}, args...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrapf(err, "git: %s", msg)
+		}
+		return nil, errors.Wrap(err, "git")
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,395 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrs "errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"go.stevenxie.me/api/pkg/zero"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+)
+
+// New creates a new Proxy, which serves modules located by locator.
+func New(locator Locator, opts ...func(*Config)) *Proxy {
+	cfg := Config{
+		CacheDir:      filepath.Join(os.TempDir(), "vaingogh-proxy"),
+		FetchInterval: 5 * time.Minute,
+		Logger:        zero.Logger(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Proxy{
+		locator:  locator,
+		cacheDir: cfg.CacheDir,
+		interval: cfg.FetchInterval,
+		log:      cfg.Logger,
+		repos:    make(map[string]*gitRepo),
+	}
+}
+
+type (
+	// A Proxy is an http.Handler that serves modules using the GOPROXY
+	// protocol, building module versions from the tags of their git repos.
+	//
+	// It is safe for concurrent use.
+	Proxy struct {
+		locator  Locator
+		cacheDir string
+		interval time.Duration
+		log      logrus.FieldLogger
+
+		mux   sync.Mutex
+		repos map[string]*gitRepo
+	}
+
+	// A Config configures a Proxy.
+	Config struct {
+		// CacheDir is the directory in which repos and module zips are cached.
+		CacheDir string
+
+		// FetchInterval is the minimum interval between fetches of a repo.
+		FetchInterval time.Duration
+
+		Logger logrus.FieldLogger
+	}
+
+	// A Locator locates the git repos that modules are stored in.
+	Locator interface {
+		// LocateModule returns the Location of the module at modulePath. If no
+		// such module exists, it returns an error that wraps ErrNotFound.
+		LocateModule(modulePath string) (*Location, error)
+	}
+
+	// A Location describes where a module is stored.
+	Location struct {
+		// RepoURL is the URL (or local path) of the git repo that contains the
+		// module.
+		RepoURL string
+
+		// Dir is the directory that contains the module, relative to the repo
+		// root.
+		Dir string
+
+		// TagPrefix is the prefix of the tags that correspond to module
+		// versions (i.e. 'sub/' for tags like 'sub/v1.2.3').
+		TagPrefix string
+	}
+
+	// An Info describes a module version, in the format expected by the
+	// 'go' command.
+	Info struct {
+		Version string
+		Time    time.Time
+	}
+)
+
+// ErrNotFound is returned when a module or module version cannot be found.
+var ErrNotFound = stderrs.New("proxy: not found")
+
+// ServeHTTP implements http.Handler.
+//
+// Requests are expected to be of the form '/<module>/@v/list',
+// '/<module>/@v/<version>.(info|mod|zip)', or '/<module>/@latest'.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if (r.Method != http.MethodGet) && (r.Method != http.MethodHead) {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := p.serve(w, r)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	p.log.
+		WithError(err).
+		WithField("path", r.URL.Path).
+		Error("Failed to serve proxy request.")
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+func (p *Proxy) serve(w http.ResponseWriter, r *http.Request) error {
+	reqpath := strings.TrimPrefix(r.URL.Path, "/")
+
+	// Handle '@latest' queries.
+	if strings.HasSuffix(reqpath, "/@latest") {
+		modpath, err := module.UnescapePath(
+			strings.TrimSuffix(reqpath, "/@latest"),
+		)
+		if err != nil {
+			return ErrNotFound
+		}
+		info, err := p.Latest(modpath)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, info)
+	}
+
+	// Handle '@v' queries.
+	i := strings.Index(reqpath, "/@v/")
+	if i < 0 {
+		return ErrNotFound
+	}
+	modpath, err := module.UnescapePath(reqpath[:i])
+	if err != nil {
+		return ErrNotFound
+	}
+	file := reqpath[i+len("/@v/"):]
+	if file == "list" {
+		versions, err := p.Versions(modpath)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		for _, v := range versions {
+			if _, err = w.Write([]byte(v + "\n")); err != nil {
+				return errors.Wrap(err, "writing response")
+			}
+		}
+		return nil
+	}
+
+	ext := path.Ext(file)
+	version, err := module.UnescapeVersion(strings.TrimSuffix(file, ext))
+	if err != nil {
+		return ErrNotFound
+	}
+	switch ext {
+	case ".info":
+		info, err := p.Info(modpath, version)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, info)
+	case ".mod":
+		data, err := p.GoMod(modpath, version)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		_, err = w.Write(data)
+		return errors.Wrap(err, "writing response")
+	case ".zip":
+		name, err := p.Zip(modpath, version)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return errors.Wrap(err, "opening zip")
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			return errors.Wrap(err, "stat-ing zip")
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		http.ServeContent(w, r, "", stat.ModTime(), f)
+		return nil
+	default:
+		return ErrNotFound
+	}
+}
+
+// Versions lists the known versions of the module at modpath.
+func (p *Proxy) Versions(modpath string) ([]string, error) {
+	_, _, tags, err := p.load(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(tags))
+	for v := range tags {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.Compare(versions[i], versions[j]) < 0
+	})
+	return versions, nil
+}
+
+// Latest returns the Info of the latest version of the module at modpath.
+// Release versions are preferred over prereleases.
+func (p *Proxy) Latest(modpath string) (*Info, error) {
+	versions, err := p.Versions(modpath)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	latest := versions[len(versions)-1]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if semver.Prerelease(versions[i]) == "" {
+			latest = versions[i]
+			break
+		}
+	}
+	return p.Info(modpath, latest)
+}
+
+// Info returns the Info of the specified version of the module at modpath.
+func (p *Proxy) Info(modpath, version string) (*Info, error) {
+	_, repo, tags, err := p.load(modpath)
+	if err != nil {
+		return nil, err
+	}
+	tag, ok := tags[version]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	t, err := repo.commitTime(tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "proxy")
+	}
+	return &Info{Version: version, Time: t}, nil
+}
+
+// GoMod returns the go.mod file of the specified version of the module at
+// modpath. If the module has no go.mod file, a minimal one is synthesized.
+func (p *Proxy) GoMod(modpath, version string) ([]byte, error) {
+	loc, repo, tags, err := p.load(modpath)
+	if err != nil {
+		return nil, err
+	}
+	tag, ok := tags[version]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	data, err := repo.readFile(tag, path.Join(loc.Dir, "go.mod"))
+	if os.IsNotExist(err) {
+		return []byte("module " + modpath + "\n"), nil
+	}
+	return data, errors.Wrap(err, "proxy")
+}
+
+// Zip returns the path to a zip archive of the specified version of the
+// module at modpath, building it if necessary.
+func (p *Proxy) Zip(modpath, version string) (name string, err error) {
+	loc, repo, tags, err := p.load(modpath)
+	if err != nil {
+		return "", err
+	}
+	tag, ok := tags[version]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	// Module versions are immutable, so serve a cached zip if one exists.
+	escaped, err := module.EscapePath(modpath)
+	if err != nil {
+		return "", ErrNotFound
+	}
+	name = filepath.Join(p.cacheDir, "zip", filepath.FromSlash(escaped),
+		version+".zip")
+	if _, err = os.Stat(name); err == nil {
+		return name, nil
+	}
+
+	// Build zip from repo files.
+	files, err := repo.files(tag, loc.Dir)
+	if err != nil {
+		return "", errors.Wrap(err, "proxy")
+	}
+	var buf bytes.Buffer
+	if err = modzip.Create(
+		&buf,
+		module.Version{Path: modpath, Version: version},
+		files,
+	); err != nil {
+		return "", errors.Wrap(err, "proxy: creating zip")
+	}
+
+	// Write zip to cache atomically.
+	if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return "", errors.Wrap(err, "proxy: creating zip cache directory")
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), version+".zip.*")
+	if err != nil {
+		return "", errors.Wrap(err, "proxy: creating zip file")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return "", errors.Wrap(err, "proxy: writing zip file")
+	}
+	if err = tmp.Close(); err != nil {
+		return "", errors.Wrap(err, "proxy: writing zip file")
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return "", errors.Wrap(err, "proxy: moving zip file")
+	}
+	return name, nil
+}
+
+// load locates the module at modpath, syncs its repo, and maps its versions
+// to their tags.
+func (p *Proxy) load(modpath string) (*Location, *gitRepo,
+	map[string]string, error) {
+	loc, err := p.locator.LocateModule(modpath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Get (or create) the local mirror of the module's repo.
+	p.mux.Lock()
+	repo, ok := p.repos[loc.RepoURL]
+	if !ok {
+		repo = newGitRepo(loc.RepoURL, p.cacheDir)
+		p.repos[loc.RepoURL] = repo
+	}
+	p.mux.Unlock()
+
+	if err = repo.sync(p.interval); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "proxy: syncing repo")
+	}
+	tags, err := repo.tags()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "proxy")
+	}
+
+	// Map valid module versions to tag refs.
+	_, pathMajor, ok := module.SplitPathVersion(modpath)
+	if !ok {
+		return nil, nil, nil, ErrNotFound
+	}
+	versions := make(map[string]string)
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, loc.TagPrefix) {
+			continue
+		}
+		v := strings.TrimPrefix(tag, loc.TagPrefix)
+		if !semver.IsValid(v) || (semver.Canonical(v) != v) {
+			continue
+		}
+		if module.CheckPathMajor(v, pathMajor) != nil {
+			continue
+		}
+		versions[v] = "refs/tags/" + tag
+	}
+	return loc, repo, versions, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	return errors.Wrap(json.NewEncoder(w).Encode(v), "writing response")
+}
@@ -0,0 +1,235 @@
+package proxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testLocator locates modules using a fixed map of module paths to
+// Locations.
+type testLocator map[string]*Location
+
+var _ Locator = testLocator{}
+
+func (tl testLocator) LocateModule(modulePath string) (*Location, error) {
+	if loc, ok := tl[modulePath]; ok {
+		return loc, nil
+	}
+	return nil, ErrNotFound
+}
+
+// newTestRepo creates a local git repo with:
+//
+//   - v1.0.0: a module without a go.mod file
+//   - v2.0.0: a module with a go.mod file declaring the '/v2' major path
+//   - sub/v1.0.0: a module in the 'sub' directory
+//
+// The returned cleanup function removes the repo.
+func newTestRepo(t *testing.T) (dir string, cleanup func()) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "vaingogh-proxy-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	defer func() {
+		if t.Failed() {
+			cleanup()
+		}
+	}()
+
+	write := func(name, contents string) {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{
+			"-c", "user.name=Test",
+			"-c", "user.email=test@example.com",
+			"-c", "commit.gpgsign=false",
+			"-c", "tag.gpgsign=false",
+		}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	git("init", "--quiet")
+	write("mod.go", "package mod\n")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1.0.0")
+
+	write("go.mod", "module example.com/mod/v2\n")
+	write("sub/go.mod", "module example.com/mod/sub\n")
+	write("sub/sub.go", "package sub\n")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v2")
+	git("tag", "v2.0.0")
+	git("tag", "sub/v1.0.0")
+	return dir, cleanup
+}
+
+// newTestProxy creates a Proxy that serves the modules in a repo created by
+// newTestRepo.
+//
+// The returned cleanup function removes the repo and the Proxy's cache.
+func newTestProxy(t *testing.T) (p *Proxy, cleanup func()) {
+	repoDir, cleanupRepo := newTestRepo(t)
+	cacheDir, err := ioutil.TempDir("", "vaingogh-proxy-cache")
+	if err != nil {
+		cleanupRepo()
+		t.Fatal(err)
+	}
+	cleanup = func() {
+		cleanupRepo()
+		os.RemoveAll(cacheDir)
+	}
+
+	p = New(
+		testLocator{
+			"example.com/mod":    {RepoURL: repoDir},
+			"example.com/mod/v2": {RepoURL: repoDir},
+			"example.com/mod/sub": {
+				RepoURL:   repoDir,
+				Dir:       "sub",
+				TagPrefix: "sub/",
+			},
+		},
+		func(cfg *Config) { cfg.CacheDir = cacheDir },
+	)
+	return p, cleanup
+}
+
+func get(t *testing.T, h http.Handler, path string) (int, []byte) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w.Code, w.Body.Bytes()
+}
+
+func TestProxy(t *testing.T) {
+	p, cleanup := newTestProxy(t)
+	defer cleanup()
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/example.com/mod/@v/list", "v1.0.0\n"},
+		{"/example.com/mod/v2/@v/list", "v2.0.0\n"},
+		{"/example.com/mod/sub/@v/list", "v1.0.0\n"},
+
+		// Synthesized go.mod, for a version without one.
+		{"/example.com/mod/@v/v1.0.0.mod", "module example.com/mod\n"},
+		{"/example.com/mod/v2/@v/v2.0.0.mod", "module example.com/mod/v2\n"},
+		{"/example.com/mod/sub/@v/v1.0.0.mod", "module example.com/mod/sub\n"},
+	}
+	for _, c := range cases {
+		status, body := get(t, p, c.path)
+		if status != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", c.path, status, body)
+			continue
+		}
+		if string(body) != c.want {
+			t.Errorf("%s: expected %q, got %q", c.path, c.want, body)
+		}
+	}
+
+	// Check version info.
+	for path, want := range map[string]string{
+		"/example.com/mod/@v/v1.0.0.info":     "v1.0.0",
+		"/example.com/mod/@latest":            "v1.0.0",
+		"/example.com/mod/v2/@latest":         "v2.0.0",
+		"/example.com/mod/sub/@v/v1.0.0.info": "v1.0.0",
+	} {
+		status, body := get(t, p, path)
+		if status != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", path, status, body)
+			continue
+		}
+		var info Info
+		if err := json.Unmarshal(body, &info); err != nil {
+			t.Errorf("%s: decoding info: %v", path, err)
+			continue
+		}
+		if (info.Version != want) || info.Time.IsZero() {
+			t.Errorf("%s: expected version %s with a time, got %+v", path, want,
+				info)
+		}
+	}
+}
+
+func TestProxyZip(t *testing.T) {
+	p, cleanup := newTestProxy(t)
+	defer cleanup()
+	cases := map[string][]string{
+		"/example.com/mod/@v/v1.0.0.zip": {
+			"example.com/mod@v1.0.0/mod.go",
+		},
+		"/example.com/mod/v2/@v/v2.0.0.zip": {
+			"example.com/mod/v2@v2.0.0/go.mod",
+			"example.com/mod/v2@v2.0.0/mod.go",
+		},
+		"/example.com/mod/sub/@v/v1.0.0.zip": {
+			"example.com/mod/sub@v1.0.0/go.mod",
+			"example.com/mod/sub@v1.0.0/sub.go",
+		},
+	}
+	for path, want := range cases {
+		status, body := get(t, p, path)
+		if status != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", path, status, body)
+			continue
+		}
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Errorf("%s: reading zip: %v", path, err)
+			continue
+		}
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: expected files %v, got %v", path, want, names)
+		}
+	}
+}
+
+func TestProxyNotFound(t *testing.T) {
+	p, cleanup := newTestProxy(t)
+	defer cleanup()
+	for _, path := range []string{
+		"/example.com/mod/@v/v1.9.9.info",
+		"/example.com/mod/@v/v1.9.9.mod",
+		"/example.com/mod/@v/v1.9.9.zip",
+		"/example.com/mod/@v/v2.0.0.info",    // wrong major path
+		"/example.com/mod/v2/@v/v1.0.0.info", // wrong major path
+		"/example.com/mod/@v/sub/v1.0.0.info",
+		"/example.com/other/@v/list",
+		"/example.com/mod/@v/v1.0.0.tar",
+		"/example.com/mod",
+	} {
+		if status, body := get(t, p, path); status != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d: %s", path, status, body)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"sort"
+	"strings"
+)
+
+type (
+	// An Event describes a change in the list of repos watched by a Watcher.
+	Event struct {
+		Previous *Snapshot
+		Current  *Snapshot
+		Diff     Diff
+	}
+
+	// A Diff describes the difference between two Snapshots.
+	Diff struct {
+		Added   []string `json:"added"`
+		Removed []string `json:"removed"`
+
+		// Changed contains repos that exist in both snapshots, but whose
+		// metadata differs.
+		Changed []string `json:"changed"`
+	}
+)
+
+// DiffSnapshots computes the Diff between the snapshots prev and curr.
+func DiffSnapshots(prev, curr *Snapshot) Diff {
+	diff := Diff{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []string{},
+	}
+	for key, r := range curr.index {
+		old, ok := prev.index[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, r.Name)
+		case !old.equal(r):
+			diff.Changed = append(diff.Changed, r.Name)
+		}
+	}
+	for key, r := range prev.index {
+		if _, ok := curr.index[key]; !ok {
+			diff.Removed = append(diff.Removed, r.Name)
+		}
+	}
+
+	// Sort results for stable output.
+	for _, names := range [][]string{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(names, func(i, j int) bool {
+			return strings.ToLower(names[i]) < strings.ToLower(names[j])
+		})
+	}
+	return diff
+}
+
+// IsEmpty returns true if the Diff contains no changes.
+func (d Diff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
@@ -33,6 +33,11 @@ func NewClient(opts ...func(*ClientConfig)) (*github.Client, error) {
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	if wrap := cfg.WrapTransport; wrap != nil {
+		client := *cfg.HTTPClient
+		client.Transport = wrap(client.Transport)
+		cfg.HTTPClient = &client
+	}
 
 	return github.NewClient(cfg.HTTPClient), nil
 }
@@ -40,4 +45,9 @@ func NewClient(opts ...func(*ClientConfig)) (*github.Client, error) {
 // ClientConfig configures a github.Client.
 type ClientConfig struct {
 	HTTPClient *http.Client
+
+	// WrapTransport, if set, wraps the transport of HTTPClient (i.e. for
+	// instrumentation). The wrapped transport may be nil, which denotes
+	// http.DefaultTransport.
+	WrapTransport func(http.RoundTripper) http.RoundTripper
 }
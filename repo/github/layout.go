@@ -0,0 +1,152 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v27/github"
+
+	"go.stevenxie.me/vaingogh/repo"
+)
+
+// NewLayoutDetector creates a new LayoutDetector.
+func NewLayoutDetector(
+	c *github.Client,
+	opts ...func(*LayoutDetectorConfig),
+) *LayoutDetector {
+	cfg := LayoutDetectorConfig{
+		TTL: time.Hour,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &LayoutDetector{
+		client: c,
+		ttl:    cfg.TTL,
+		cache:  make(map[string]cachedLayout),
+	}
+}
+
+type (
+	// A LayoutDetector detects the layout of modules in GitHub repos.
+	//
+	// The v0 / v1 module is assumed to be at the root of the repo's default
+	// branch, which is known without calling the GitHub API.
+	//
+	// Other major versions are only detected if they are known to exist (see
+	// repo.Repo.HasMajor). Such a major version is assumed to be in a
+	// subdirectory named after its major version suffix (i.e. 'v2/') if that
+	// subdirectory contains a go.mod file; otherwise, on a branch named after
+	// its suffix if such a branch exists; and otherwise, at the root of the
+	// repo's default branch.
+	//
+	// Detected layouts are cached. It is safe for concurrent use.
+	LayoutDetector struct {
+		client *github.Client
+		ttl    time.Duration
+
+		mux   sync.Mutex
+		cache map[string]cachedLayout
+	}
+
+	// A LayoutDetectorConfig configures a LayoutDetector.
+	LayoutDetectorConfig struct {
+		TTL time.Duration // how long to cache detected layouts for
+	}
+
+	cachedLayout struct {
+		layout  repo.Layout
+		expires time.Time
+	}
+)
+
+var _ repo.LayoutService = (*LayoutDetector)(nil)
+
+// ModuleLayout detects the Layout for the specified major version of the
+// module in r.
+func (ld *LayoutDetector) ModuleLayout(r *repo.Repo, major string) (
+	*repo.Layout, error) {
+	if major == "" {
+		return &repo.Layout{Branch: r.DefaultBranch}, nil
+	}
+	if !r.HasMajor(major) {
+		return nil, repo.ErrUnknownMajor
+	}
+
+	key := strings.ToLower(r.Name) + "@" + major
+	ld.mux.Lock()
+	cached, ok := ld.cache[key]
+	ld.mux.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		layout := cached.layout
+		return &layout, nil
+	}
+
+	layout, err := ld.detect(r, major)
+	if err != nil {
+		return nil, errors.Wrapf(err, "github: detecting layout of '%s'",
+			r.Name)
+	}
+
+	ld.mux.Lock()
+	ld.cache[key] = cachedLayout{
+		layout:  *layout,
+		expires: time.Now().Add(ld.ttl),
+	}
+	ld.mux.Unlock()
+	return layout, nil
+}
+
+func (ld *LayoutDetector) detect(r *repo.Repo, major string) (*repo.Layout,
+	error) {
+	owner, name, err := splitFullName(r.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check for a major version subdirectory.
+	var (
+		ctx    = context.Background()
+		layout = &repo.Layout{Branch: r.DefaultBranch}
+	)
+	_, _, res, err := ld.client.Repositories.GetContents(
+		ctx,
+		owner, name,
+		major+"/go.mod",
+		&github.RepositoryContentGetOptions{Ref: layout.Branch},
+	)
+	if err == nil {
+		layout.Dir = major
+		return layout, nil
+	}
+	if !isNotFound(res) {
+		return nil, errors.Wrap(err, "getting major version go.mod")
+	}
+
+	// Check for a major version branch.
+	_, res, err = ld.client.Repositories.GetBranch(ctx, owner, name, major)
+	if err == nil {
+		layout.Branch = major
+		return layout, nil
+	}
+	if !isNotFound(res) {
+		return nil, errors.Wrap(err, "getting major version branch")
+	}
+	return layout, nil
+}
+
+func splitFullName(fullName string) (owner, name string, err error) {
+	parts := strings.SplitN(fullName, "/", 2)
+	if len(parts) != 2 {
+		return "", "", errors.Newf("invalid repo name '%s'", fullName)
+	}
+	return parts[0], parts[1], nil
+}
+
+func isNotFound(res *github.Response) bool {
+	return (res != nil) && (res.StatusCode == http.StatusNotFound)
+}
@@ -0,0 +1,36 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/errors"
+
+	"go.stevenxie.me/vaingogh/repo"
+)
+
+func TestLayoutDetectorWithoutAPICalls(t *testing.T) {
+	// The detector has no client, so any GitHub API call would panic.
+	ld := NewLayoutDetector(nil)
+	r := &repo.Repo{
+		Name:          "user/mod",
+		DefaultBranch: "main",
+		Modules: []*repo.ModuleVersions{
+			{Versions: []string{"v1.0.0"}},
+			{Major: "v2", Versions: []string{"v2.0.0"}},
+		},
+	}
+
+	layout, err := ld.ModuleLayout(r, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *layout != (repo.Layout{Branch: "main"}) {
+		t.Errorf("expected the default branch, got %+v", layout)
+	}
+	for _, major := range []string{"v3", "v99"} {
+		if _, err := ld.ModuleLayout(r, major); !errors.Is(err,
+			repo.ErrUnknownMajor) {
+			t.Errorf("%s: expected ErrUnknownMajor, got: %v", major, err)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	stderrs "errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/google/go-github/v27/github"
@@ -41,9 +44,23 @@ type (
 		client      *github.Client
 		concurrency int
 
-		user    string
-		checked bool
-		isOrg   bool
+		user     string
+		checkMux sync.Mutex // guards checked and isOrg
+		checked  bool
+		isOrg    bool
+
+		// details caches the details of each listed repo, so that they're only
+		// requested again once the repo is pushed to.
+		detailsMux sync.Mutex
+		details    map[string]*repoDetails
+	}
+
+	// repoDetails are the details of a repo that require additional requests
+	// to determine, as of the time that the repo was last pushed to.
+	repoDetails struct {
+		pushedAt time.Time
+		isGo     bool
+		modules  []*repo.ModuleVersions
 	}
 
 	// A ListerConfig configures a Lister.
@@ -55,17 +72,15 @@ type (
 var _ repo.ListerService = (*Lister)(nil)
 
 // ListGoRepos lists all the repos that use Go.
-func (l *Lister) ListGoRepos() ([]string, error) {
-	if err := l.checkUser(); err != nil {
+func (l *Lister) ListGoRepos() ([]*repo.Repo, error) {
+	isOrg, err := l.checkUser()
+	if err != nil {
 		return nil, errors.Wrap(err, "github: checking user type")
 	}
 
 	// List all repos by l.user.
-	var (
-		repos []*github.Repository
-		err   error
-	)
-	if l.isOrg {
+	var repos []*github.Repository
+	if isOrg {
 		repos, _, err = l.client.Repositories.ListByOrg(
 			context.Background(),
 			l.user,
@@ -88,11 +103,13 @@ func (l *Lister) ListGoRepos() ([]string, error) {
 
 	// Prepare to consolidate async work results.
 	var (
-		results = make(chan string)
-		gorepos = make([]string, 0, len(repos))
-		done    = make(chan zero.Struct)
+		details    = make(map[string]*repoDetails, len(repos))
+		detailsMux sync.Mutex
+		results    = make(chan *repo.Repo)
+		gorepos    = make([]*repo.Repo, 0, len(repos))
+		done       = make(chan zero.Struct)
 	)
-	go func(results <-chan string, done chan<- zero.Struct) {
+	go func(results <-chan *repo.Repo, done chan<- zero.Struct) {
 		for result := range results {
 			gorepos = append(gorepos, result)
 		}
@@ -119,23 +136,25 @@ func (l *Lister) ListGoRepos() ([]string, error) {
 			group.Go(func() error {
 				defer sem.Release(1)
 
-				// Make request with groupctx, so that it will be cancelled if the group
-				// is cancelled.
-				languages, _, err := svc.ListLanguages(
-					groupctx,
-					repo.GetOwner().GetLogin(),
-					repo.GetName(),
-				)
+				// Make requests with groupctx, so that they will be cancelled if
+				// the group is cancelled.
+				d, err := l.repoDetails(groupctx, svc, repo)
 				if err != nil {
-					return errors.Wrapf(err, "listing languages for '%s'",
-						repo.GetFullName())
+					return err
 				}
+				detailsMux.Lock()
+				details[repo.GetFullName()] = d
+				detailsMux.Unlock()
 
-				// Send repo name to results channel if it language analysis results
-				// contain 'Go'.
-				if _, ok := languages["Go"]; ok {
-					results <- repo.GetFullName()
+				// Skip repo if its language analysis results don't contain 'Go'.
+				if !d.isGo {
+					return nil
 				}
+
+				// Send repo to results channel.
+				converted := convertRepo(repo, latestTag(d.modules))
+				converted.Modules = d.modules
+				results <- converted
 				return nil
 			})
 		}(repo, l.client.Repositories)
@@ -150,17 +169,108 @@ func (l *Lister) ListGoRepos() ([]string, error) {
 	// Wait for results to finish consolidating.
 	<-done
 
+	// Replace the cached details, which drops those of repos that are no
+	// longer listed.
+	l.detailsMux.Lock()
+	l.details = details
+	l.detailsMux.Unlock()
+
 	return gorepos, nil
 }
 
-func (l *Lister) checkUser() error {
+// repoDetails returns the details of r, which are cached until r is next
+// pushed to.
+func (l *Lister) repoDetails(
+	ctx context.Context,
+	svc *github.RepositoriesService,
+	r *github.Repository,
+) (*repoDetails, error) {
+	pushedAt := r.GetPushedAt().Time
+	l.detailsMux.Lock()
+	cached, ok := l.details[r.GetFullName()]
+	l.detailsMux.Unlock()
+	if ok && cached.pushedAt.Equal(pushedAt) {
+		return cached, nil
+	}
+
+	d := &repoDetails{pushedAt: pushedAt}
+	languages, _, err := svc.ListLanguages(
+		ctx,
+		r.GetOwner().GetLogin(),
+		r.GetName(),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing languages for '%s'",
+			r.GetFullName())
+	}
+	if _, d.isGo = languages["Go"]; !d.isGo {
+		return d, nil
+	}
+
+	// Discover the versions of the modules in the repo.
+	if d.modules, err = listModules(
+		ctx,
+		svc,
+		r.GetOwner().GetLogin(),
+		r.GetName(),
+	); err != nil {
+		return nil, errors.Wrapf(err, "discovering versions for '%s'",
+			r.GetFullName())
+	}
+	return d, nil
+}
+
+// latestTag returns the tag of the latest version of the highest major
+// version of the module at the root of a repo, or "" if it has no versions.
+func latestTag(modules []*repo.ModuleVersions) string {
+	// Modules are ordered by directory and then by major version, so the
+	// last root module with a latest version is the one to use.
+	tag := ""
+	for _, mv := range modules {
+		if (mv.Dir == "") && (mv.Latest != "") {
+			tag = mv.Tag(mv.Latest)
+		}
+	}
+	return tag
+}
+
+// convertRepo converts a github.Repository into a repo.Repo.
+func convertRepo(r *github.Repository, latestTag string) *repo.Repo {
+	converted := &repo.Repo{
+		Name:          r.GetFullName(),
+		Description:   r.GetDescription(),
+		URL:           r.GetHTMLURL(),
+		DefaultBranch: r.GetDefaultBranch(),
+		LatestTag:     latestTag,
+		Topics:        r.Topics,
+		Stars:         r.GetStargazersCount(),
+		UpdatedAt:     r.GetPushedAt().Time,
+	}
+	if license := r.GetLicense(); license != nil {
+		converted.License = &repo.License{
+			Name:   license.GetName(),
+			SPDXID: license.GetSPDXID(),
+		}
+	}
+	return converted
+}
+
+// checkUser determines whether l.user is a user or an organization, and
+// reports whether it is an organization. The result is remembered once it is
+// determined successfully.
+//
+// It is safe for concurrent use, since the Lister may be used by a Watcher's
+// poller and refreshes at the same time.
+func (l *Lister) checkUser() (isOrg bool, err error) {
+	l.checkMux.Lock()
+	defer l.checkMux.Unlock()
 	if l.checked {
-		return nil
+		return l.isOrg, nil
 	}
 	{
 		user, _, err := l.client.Users.Get(context.Background(), l.user)
 		if err != nil {
-			return errors.Wrap(err, "getting user details")
+			return false, errors.Wrap(err, "getting user details")
 		}
 		if user != nil {
 			goto Checked
@@ -169,18 +279,18 @@ func (l *Lister) checkUser() error {
 	{
 		org, _, err := l.client.Organizations.Get(context.Background(), l.user)
 		if err != nil {
-			return errors.Wrap(err, "getting org details")
+			return false, errors.Wrap(err, "getting org details")
 		}
 		if org != nil {
 			l.isOrg = true
 			goto Checked
 		}
 	}
-	return ErrUserNotExists
+	return false, ErrUserNotExists
 
 Checked:
 	l.checked = true
-	return nil
+	return l.isOrg, nil
 }
 
 // ErrUserNotExists is returned by a RepoLister when it is unable to locate
@@ -191,3 +301,11 @@ var ErrUserNotExists = stderrs.New("no such user or organization exists")
 func (l *Lister) DeriveRepoFullName(partial string) (repo string) {
 	return fmt.Sprintf("%s/%s", l.user, partial)
 }
+
+// DerivePartialName derives the partial name of a repo from its full name.
+func (l *Lister) DerivePartialName(repo string) (partial string) {
+	if i := strings.IndexByte(repo, '/'); i > -1 {
+		return repo[i+1:]
+	}
+	return repo
+}
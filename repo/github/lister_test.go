@@ -0,0 +1,117 @@
+package github
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v27/github"
+)
+
+// fakeGitHub serves the parts of the GitHub API that a Lister uses, for a
+// user named 'user' with a single Go repo named 'mod', and counts the
+// requests made to each path.
+type fakeGitHub struct {
+	mux      sync.Mutex
+	pushedAt string
+	requests map[string]int
+}
+
+func (fg *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fg.mux.Lock()
+	fg.requests[r.URL.Path]++
+	pushedAt := fg.pushedAt
+	fg.mux.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/users/user":
+		fmt.Fprint(w, `{"login": "user", "type": "User"}`)
+	case "/users/user/repos":
+		fmt.Fprintf(w, `[{
+			"name": "mod",
+			"full_name": "user/mod",
+			"owner": {"login": "user"},
+			"default_branch": "main",
+			"pushed_at": %q
+		}]`, pushedAt)
+	case "/repos/user/mod/languages":
+		fmt.Fprint(w, `{"Go": 1000}`)
+	case "/repos/user/mod/tags":
+		fmt.Fprint(w, `[{"name": "v1.0.0"}, {"name": "v1.1.0"}]`)
+	case "/repos/user/mod/contents/go.mod":
+		content := base64.StdEncoding.EncodeToString(
+			[]byte("module example.com/mod\n\nretract v1.1.0\n"),
+		)
+		fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`,
+			content)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (fg *fakeGitHub) count(path string) int {
+	fg.mux.Lock()
+	defer fg.mux.Unlock()
+	return fg.requests[path]
+}
+
+func TestListerCachesDetails(t *testing.T) {
+	fg := &fakeGitHub{
+		pushedAt: "2020-01-01T00:00:00Z",
+		requests: make(map[string]int),
+	}
+	srv := httptest.NewServer(fg)
+	defer srv.Close()
+
+	client := github.NewClient(srv.Client())
+	var err error
+	if client.BaseURL, err = url.Parse(srv.URL + "/"); err != nil {
+		t.Fatal(err)
+	}
+	l := NewLister(client, "user")
+
+	list := func() {
+		repos, err := l.ListGoRepos()
+		if err != nil {
+			t.Fatalf("listing repos: %v", err)
+		}
+		if len(repos) != 1 {
+			t.Fatalf("expected 1 repo, got %d", len(repos))
+		}
+		r := repos[0]
+		if (len(r.Modules) != 1) || (r.Modules[0].Latest != "v1.0.0") ||
+			(r.LatestTag != "v1.0.0") {
+			t.Errorf("expected latest version v1.0.0 (with v1.1.0 retracted), "+
+				"got modules %+v and tag %q", r.Modules, r.LatestTag)
+		}
+	}
+	expect := func(path string, n int) {
+		if got := fg.count(path); got != n {
+			t.Errorf("expected %d requests to '%s', got %d", n, path, got)
+		}
+	}
+
+	// Unchanged repos are only listed again.
+	list()
+	list()
+	expect("/users/user/repos", 2)
+	expect("/repos/user/mod/languages", 1)
+	expect("/repos/user/mod/tags", 1)
+	expect("/repos/user/mod/contents/go.mod", 1)
+	expect("/repos/user/mod/releases/latest", 0)
+
+	// Repos that have been pushed to are checked again.
+	fg.mux.Lock()
+	fg.pushedAt = "2020-01-02T00:00:00Z"
+	fg.mux.Unlock()
+	list()
+	expect("/users/user/repos", 3)
+	expect("/repos/user/mod/languages", 2)
+	expect("/repos/user/mod/tags", 2)
+	expect("/repos/user/mod/contents/go.mod", 2)
+}
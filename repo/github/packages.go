@@ -0,0 +1,93 @@
+package github
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v27/github"
+
+	"go.stevenxie.me/vaingogh/repo"
+)
+
+// NewPackageLister creates a new PackageLister.
+func NewPackageLister(c *github.Client) *PackageLister {
+	return &PackageLister{client: c}
+}
+
+// A PackageLister lists the Go packages in GitHub repos, using their Git
+// trees. It is safe for concurrent use.
+type PackageLister struct {
+	client *github.Client
+}
+
+var _ repo.PackageService = (*PackageLister)(nil)
+
+// ListPackages lists the directories on branch of the repo named fullName
+// that contain Go packages.
+//
+// Directories that the 'go' command ignores (i.e. 'testdata', and those whose
+// names begin with '.' or '_') are skipped, as are vendored packages.
+func (pl *PackageLister) ListPackages(fullName, branch string) ([]string,
+	error) {
+	owner, name, err := splitFullName(fullName)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if branch == "" {
+		r, _, err := pl.client.Repositories.Get(ctx, owner, name)
+		if err != nil {
+			return nil, errors.Wrap(err, "github: getting repo details")
+		}
+		branch = r.GetDefaultBranch()
+	}
+
+	tree, _, err := pl.client.Git.GetTree(ctx, owner, name, branch, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "github: getting tree of '%s'", fullName)
+	}
+	if tree.GetTruncated() {
+		return nil, errors.Newf("github: tree of '%s' is too large to list",
+			fullName)
+	}
+
+	dirs := make(map[string]bool)
+	for _, entry := range tree.Entries {
+		p := entry.GetPath()
+		if (entry.GetType() != "blob") || !strings.HasSuffix(p, ".go") ||
+			strings.HasSuffix(p, "_test.go") {
+			continue
+		}
+		dir := path.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+		if !isIgnoredDir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	pkgs := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		pkgs = append(pkgs, dir)
+	}
+	sort.Strings(pkgs)
+	return pkgs, nil
+}
+
+// isIgnoredDir returns true if the 'go' command ignores packages in dir.
+func isIgnoredDir(dir string) bool {
+	if dir == "" {
+		return false
+	}
+	for _, elem := range strings.Split(dir, "/") {
+		if (elem == "testdata") || (elem == "vendor") ||
+			strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package github
+
+import (
+	"context"
+	"path"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v27/github"
+
+	"go.stevenxie.me/vaingogh/repo"
+)
+
+// listModules lists the semver tags of a repo, and groups them into the
+// modules that they version.
+//
+// Retractions are read from the go.mod file at the latest version of each
+// module.
+func listModules(
+	ctx context.Context,
+	svc *github.RepositoriesService,
+	owner, name string,
+) ([]*repo.ModuleVersions, error) {
+	var (
+		tags []string
+		opt  = github.ListOptions{PerPage: 100}
+	)
+	for {
+		page, res, err := svc.ListTags(ctx, owner, name, &opt)
+		if err != nil {
+			if isNotFound(res) { // i.e. the repo is empty
+				break
+			}
+			return nil, errors.Wrap(err, "listing tags")
+		}
+		for _, tag := range page {
+			tags = append(tags, tag.GetName())
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opt.Page = res.NextPage
+	}
+
+	modules := repo.ParseTags(tags)
+	for _, mv := range modules {
+		if mv.Latest == "" {
+			continue
+		}
+		gomod, err := readGoMod(ctx, svc, owner, name, mv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading go.mod at '%s'",
+				mv.Tag(mv.Latest))
+		}
+		if gomod == nil {
+			continue
+		}
+
+		// A malformed go.mod only prevents the go command from reading
+		// retractions, so it shouldn't prevent the repo from being listed.
+		_ = mv.Retract(gomod)
+	}
+	return modules, nil
+}
+
+// readGoMod reads the go.mod file at the latest version of mv, or returns
+// nil if there isn't one.
+//
+// For major versions, the go.mod file in the major version subdirectory is
+// preferred to the one at the root of the module.
+func readGoMod(
+	ctx context.Context,
+	svc *github.RepositoriesService,
+	owner, name string,
+	mv *repo.ModuleVersions,
+) ([]byte, error) {
+	candidates := []string{path.Join(mv.Dir, "go.mod")}
+	if mv.Major != "" {
+		candidates = append(
+			[]string{path.Join(mv.Dir, mv.Major, "go.mod")},
+			candidates...,
+		)
+	}
+
+	opt := github.RepositoryContentGetOptions{Ref: mv.Tag(mv.Latest)}
+	for _, candidate := range candidates {
+		file, _, res, err := svc.GetContents(ctx, owner, name, candidate, &opt)
+		if err != nil {
+			if isNotFound(res) {
+				continue
+			}
+			return nil, err
+		}
+		if file == nil { // candidate is a directory
+			continue
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding content")
+		}
+		return []byte(content), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	stderrs "errors"
+	"strings"
+)
+
+// ErrUnknownMajor is returned by LayoutServices for major versions of modules
+// that aren't known to exist.
+var ErrUnknownMajor = stderrs.New("repo: unknown major version")
+
+type (
+	// A Layout describes where a module is located within its repo.
+	Layout struct {
+		// Branch is the branch that contains the module. If empty, the repo's
+		// default branch is assumed.
+		Branch string `yaml:"branch" json:"branch,omitempty"`
+
+		// Dir is the directory that contains the module, relative to the repo
+		// root.
+		Dir string `yaml:"dir" json:"dir,omitempty"`
+	}
+
+	// A LayoutOverrider is a LayoutService that returns preconfigured Layouts
+	// for particular repos and major versions, and otherwise falls back to
+	// an underlying LayoutService.
+	LayoutOverrider struct {
+		svc       LayoutService
+		overrides map[string]map[string]Layout
+	}
+)
+
+// NewLayoutOverrider creates a new LayoutOverrider.
+//
+// overrides maps full repo names to major version suffixes (i.e. 'v2') to
+// Layouts; the empty suffix refers to the v0 / v1 module. If svc is nil,
+// modules without overrides are assumed to be located at the root of their
+// repo's default branch.
+func NewLayoutOverrider(
+	svc LayoutService,
+	overrides map[string]map[string]Layout,
+) *LayoutOverrider {
+	// Normalize repo names, since they are case-insensitive.
+	normalized := make(map[string]map[string]Layout, len(overrides))
+	for repo, majors := range overrides {
+		normalized[strings.ToLower(repo)] = majors
+	}
+	return &LayoutOverrider{
+		svc:       svc,
+		overrides: normalized,
+	}
+}
+
+var _ LayoutService = (*LayoutOverrider)(nil)
+
+// ModuleLayout returns the Layout for the specified major version of the
+// module in r.
+//
+// Major versions that are overridden are always known, even if they haven't
+// been tagged.
+func (lo *LayoutOverrider) ModuleLayout(r *Repo, major string) (*Layout,
+	error) {
+	if majors, ok := lo.overrides[strings.ToLower(r.Name)]; ok {
+		if layout, ok := majors[major]; ok {
+			return &layout, nil
+		}
+	}
+	if !r.HasMajor(major) {
+		return nil, ErrUnknownMajor
+	}
+	if lo.svc == nil {
+		return &Layout{Branch: r.DefaultBranch}, nil
+	}
+	return lo.svc.ModuleLayout(r, major)
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestLayoutOverrider(t *testing.T) {
+	lo := NewLayoutOverrider(nil, map[string]map[string]Layout{
+		"User/Mod": {"v3": {Branch: "v3-dev"}},
+	})
+	r := &Repo{
+		Name:          "user/mod",
+		DefaultBranch: "main",
+		Modules: []*ModuleVersions{
+			{Major: "v2", Dir: "v2", Versions: []string{"v2.0.0"}},
+			{Major: "v4", Dir: "sub", Versions: []string{"v4.0.0"}},
+		},
+	}
+	cases := []struct {
+		major string
+		want  *Layout // nil if ErrUnknownMajor is expected
+	}{
+		{"", &Layout{Branch: "main"}},
+		{"v2", &Layout{Branch: "main"}},
+		{"v3", &Layout{Branch: "v3-dev"}}, // overridden, but not tagged
+		{"v4", nil},                       // only tagged in a subdirectory
+		{"v5", nil},
+	}
+	for _, c := range cases {
+		layout, err := lo.ModuleLayout(r, c.major)
+		if c.want == nil {
+			if !errors.Is(err, ErrUnknownMajor) {
+				t.Errorf("%q: expected ErrUnknownMajor, got (%+v, %v)", c.major,
+					layout, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.major, err)
+			continue
+		}
+		if *layout != *c.want {
+			t.Errorf("%q: expected %+v, got %+v", c.major, c.want, layout)
+		}
+	}
+}
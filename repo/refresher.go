@@ -0,0 +1,42 @@
+package repo
+
+// JoinRefreshers returns a RefresherService that refreshes each of rs in turn,
+// and combines their results.
+//
+// If any of rs fails to refresh, the remaining refreshers are still
+// refreshed, and the first error is returned.
+func JoinRefreshers(rs ...RefresherService) RefresherService {
+	if len(rs) == 1 {
+		return rs[0]
+	}
+	return joinedRefresher(rs)
+}
+
+type joinedRefresher []RefresherService
+
+var _ RefresherService = (joinedRefresher)(nil)
+
+func (jr joinedRefresher) Refresh() (*RefreshResult, error) {
+	var (
+		joined   = new(RefreshResult)
+		firstErr error
+	)
+	for _, r := range jr {
+		res, err := r.Refresh()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		joined.Diff.Added = append(joined.Diff.Added, res.Diff.Added...)
+		joined.Diff.Removed = append(joined.Diff.Removed, res.Diff.Removed...)
+		joined.Diff.Changed = append(joined.Diff.Changed, res.Diff.Changed...)
+		joined.NumRepos += res.NumRepos
+		joined.Duration += res.Duration
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return joined, nil
+}
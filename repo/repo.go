@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"strings"
+	"time"
+)
+
+// A Repo describes a Go repository, and its metadata.
+//
+// Repos are shared between Snapshots, and so must not be modified after they
+// are listed.
+type Repo struct {
+	// Name is the full name of the repo, i.e. 'user/repo'.
+	Name string `json:"name"`
+
+	Description   string    `json:"description,omitempty"`
+	URL           string    `json:"url,omitempty"`
+	DefaultBranch string    `json:"defaultBranch,omitempty"`
+	License       *License  `json:"license,omitempty"`
+	LatestTag     string    `json:"latestTag,omitempty"`
+	Topics        []string  `json:"topics,omitempty"`
+	Stars         int       `json:"stars"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+
+	// Modules describes the tagged versions of each of the modules in the
+	// repo, if known.
+	Modules []*ModuleVersions `json:"modules,omitempty"`
+}
+
+// A License describes the license of a Repo.
+type License struct {
+	Name   string `json:"name"`
+	SPDXID string `json:"spdxId,omitempty"`
+}
+
+// String returns the full name of the repo.
+func (r *Repo) String() string { return r.Name }
+
+// FindModule finds the versions of the module with the major version suffix
+// major that contains the package at subpath (relative to the repo root).
+//
+// It returns nil if no tagged module contains the package.
+func (r *Repo) FindModule(subpath, major string) *ModuleVersions {
+	var found *ModuleVersions
+	for _, mv := range r.Modules {
+		if mv.Major != major {
+			continue
+		}
+		if (mv.Dir != "") && (subpath != mv.Dir) &&
+			!strings.HasPrefix(subpath, mv.Dir+"/") {
+			continue
+		}
+		if (found == nil) || (len(mv.Dir) > len(found.Dir)) {
+			found = mv
+		}
+	}
+	return found
+}
+
+// HasMajor returns true if the repo's root module is known to have been
+// tagged with the major version suffix major (either at the repo root, or in a
+// major version subdirectory).
+//
+// The empty suffix, which refers to the v0 / v1 module, is always known.
+func (r *Repo) HasMajor(major string) bool {
+	if major == "" {
+		return true
+	}
+	for _, mv := range r.Modules {
+		if (mv.Major == major) && ((mv.Dir == "") || (mv.Dir == major)) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal returns true if r and other contain the same metadata.
+func (r *Repo) equal(other *Repo) bool {
+	if (r.License == nil) != (other.License == nil) {
+		return false
+	}
+	if (r.License != nil) && (*r.License != *other.License) {
+		return false
+	}
+	if len(r.Modules) != len(other.Modules) {
+		return false
+	}
+	for i := range r.Modules {
+		if !r.Modules[i].equal(other.Modules[i]) {
+			return false
+		}
+	}
+	return (r.Name == other.Name) &&
+		(r.Description == other.Description) &&
+		(r.URL == other.URL) &&
+		(r.DefaultBranch == other.DefaultBranch) &&
+		(r.LatestTag == other.LatestTag) &&
+		stringsEqual(r.Topics, other.Topics) &&
+		(r.Stars == other.Stars) &&
+		r.UpdatedAt.Equal(other.UpdatedAt)
+}
@@ -3,14 +3,63 @@ package repo
 type (
 	// A ListerService lists the Go repositories from VCS platforms like GitHub.
 	ListerService interface {
-		ListGoRepos() ([]string, error)
+		ListGoRepos() ([]*Repo, error)
 		DeriveRepoFullName(partial string) (repo string)
+		DerivePartialName(repo string) (partial string)
 	}
 
 	// A ValidatorService can validate repos (in terms of whether or they not
 	// they are an existing repo that contains Go).
 	ValidatorService interface {
 		IsRepoValid(repo string) (bool, error)
+		LookupRepo(name string) (*Repo, bool, error)
 		DeriveRepoFullName(partial string) (repo string)
+		DerivePartialName(repo string) (partial string)
+	}
+
+	// A SnapshotService provides snapshots of the list of Go repositories.
+	SnapshotService interface {
+		Snapshot() *Snapshot
+	}
+
+	// A LayoutService determines where a particular major version of a module
+	// is located within its repo (i.e. on a 'v2' branch, or in a 'v2/'
+	// subdirectory).
+	//
+	// An empty major refers to the v0 / v1 module. Major versions that aren't
+	// known to exist (see Repo.HasMajor) result in an error marked with
+	// ErrUnknownMajor.
+	LayoutService interface {
+		ModuleLayout(r *Repo, major string) (*Layout, error)
+	}
+
+	// A PackageService lists the Go packages in a repo.
+	PackageService interface {
+		// ListPackages lists the directories on branch that contain Go
+		// packages, relative to the repo root (where "" refers to the root
+		// itself). If branch is empty, the repo's default branch is used.
+		ListPackages(repo, branch string) ([]string, error)
+	}
+
+	// A StatusService reports the Status of a source of Go repositories.
+	StatusService interface {
+		Status() Status
+	}
+
+	// A RefresherService can immediately refresh its list of Go repositories.
+	RefresherService interface {
+		Refresh() (*RefreshResult, error)
+	}
+
+	// A SubscriberService notifies subscribers whenever the list of Go
+	// repositories changes.
+	SubscriberService interface {
+		Subscribe(fn func(Event)) (unsubscribe func())
+	}
+
+	// A NotifierService notifies external parties of changes to the list of
+	// Go repositories.
+	NotifierService interface {
+		Notify(Event) error
 	}
 )
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"strings"
+	"time"
+)
+
+// NewSnapshot creates a new Snapshot from a list of repos, and the error (if
+// any) that was encountered while listing them.
+func NewSnapshot(repos []*Repo, err error) *Snapshot {
+	snap := &Snapshot{
+		repos: make([]*Repo, len(repos)),
+		index: make(map[string]*Repo, len(repos)),
+		err:   err,
+		time:  time.Now(),
+	}
+	if err == nil {
+		snap.listed = snap.time
+	}
+	copy(snap.repos, repos)
+	for _, r := range repos {
+		snap.index[strings.ToLower(r.Name)] = r
+	}
+	return snap
+}
+
+// emptySnapshot returns a Snapshot for a list of repos that has never been
+// listed.
+func emptySnapshot() *Snapshot {
+	return &Snapshot{index: make(map[string]*Repo)}
+}
+
+// A Snapshot is an immutable, indexed view of a list of repos at a particular
+// point in time.
+//
+// Repo lookups are case-insensitive, since GitHub repo names are
+// case-insensitive. It is safe for concurrent use.
+type Snapshot struct {
+	repos []*Repo
+	index map[string]*Repo // maps lowercased names to repos
+	err   error
+	time  time.Time
+
+	// listed is the time at which the repos were last listed successfully.
+	listed time.Time
+}
+
+// Repos returns a copy of the repos in the snapshot.
+func (s *Snapshot) Repos() []*Repo {
+	repos := make([]*Repo, len(s.repos))
+	copy(repos, s.repos)
+	return repos
+}
+
+// Len returns the number of repos in the snapshot.
+func (s *Snapshot) Len() int { return len(s.repos) }
+
+// Err returns the error that was encountered while listing the repos in the
+// snapshot, if any.
+func (s *Snapshot) Err() error { return s.err }
+
+// Time returns the time at which the snapshot was taken. It is zero if the
+// repos have never been listed.
+func (s *Snapshot) Time() time.Time { return s.time }
+
+// ListedAt returns the time at which the repos in the snapshot were last
+// listed successfully. It is zero if the repos have never been listed
+// successfully.
+func (s *Snapshot) ListedAt() time.Time { return s.listed }
+
+// Lookup returns the Repo named name, and true if it is in the snapshot.
+// The lookup is case-insensitive.
+func (s *Snapshot) Lookup(name string) (r *Repo, ok bool) {
+	r, ok = s.index[strings.ToLower(name)]
+	return r, ok
+}
+
+// Contains returns true if the repo named name is in the snapshot.
+func (s *Snapshot) Contains(name string) bool {
+	_, ok := s.Lookup(name)
+	return ok
+}
@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// A Status describes the health of a source of Go repositories.
+type Status struct {
+	Source      string    `json:"source"`
+	Healthy     bool      `json:"healthy"`
+	NumRepos    int       `json:"numRepos"`
+	LastRefresh time.Time `json:"lastRefresh"`
+	LastSuccess time.Time `json:"lastSuccess"`
+
+	// LastError describes the class of error that caused the latest listing
+	// to fail, if any. It omits the error's details, which may include
+	// upstream URLs or responses; those are logged by the Watcher instead.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Classes of listing errors, as reported by Status.LastError.
+const (
+	StatusErrTimeout = "timeout"
+	StatusErrListing = "listing failed"
+)
+
+// Status returns the Status of the Watcher's source.
+//
+// A source is healthy if it has been listed at least once, and its latest
+// listing succeeded.
+func (w *Watcher) Status() Status {
+	snap := w.Snapshot()
+	status := Status{
+		Source:      w.source,
+		NumRepos:    snap.Len(),
+		LastRefresh: snap.Time(),
+		LastSuccess: snap.ListedAt(),
+	}
+	if err := snap.Err(); err != nil {
+		status.LastError = statusErrClass(err)
+	}
+	status.Healthy = !status.LastSuccess.IsZero() && (snap.Err() == nil)
+	return status
+}
+
+// statusErrClass returns the class of a listing error, for use as
+// Status.LastError.
+func statusErrClass(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return StatusErrTimeout
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return StatusErrTimeout
+	}
+	return StatusErrListing
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"go.stevenxie.me/api/pkg/zero"
+)
+
+func TestStatusOmitsErrorDetails(t *testing.T) {
+	w := &Watcher{log: zero.Logger(), subs: make(map[int]func(Event))}
+	w.snapshot.Store(emptySnapshot())
+
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{
+			err:  errors.New("GET https://api.github.com/users/secret: 403"),
+			want: StatusErrListing,
+		},
+		{
+			err:  errors.Wrap(context.DeadlineExceeded, "listing repos"),
+			want: StatusErrTimeout,
+		},
+	}
+	for _, c := range cases {
+		w.update(nil, c.err)
+		status := w.Status()
+		if status.LastError != c.want {
+			t.Errorf("expected LastError %q, got %q", c.want, status.LastError)
+		}
+		if strings.Contains(status.LastError, "github") {
+			t.Errorf("LastError leaks error details: %q", status.LastError)
+		}
+		if status.Healthy {
+			t.Error("expected source to be unhealthy")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package repo
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// ModuleVersions describes the tagged versions of a module within a Repo.
+type ModuleVersions struct {
+	// Dir is the directory of the module relative to the repo root, which
+	// prefixes its tags (i.e. 'sub' for tags like 'sub/v1.2.3'). It is empty
+	// for modules tagged at the repo root.
+	Dir string `json:"dir,omitempty"`
+
+	// Major is the major version suffix of the module (i.e. 'v2'), if any.
+	Major string `json:"major,omitempty"`
+
+	// Versions are the module's valid semver versions, in ascending order.
+	Versions []string `json:"versions"`
+
+	// Latest is the latest version of the module that has not been retracted,
+	// preferring releases over prereleases.
+	Latest string `json:"latest,omitempty"`
+
+	// Prerelease is true if Latest is a prerelease version.
+	Prerelease bool `json:"prerelease,omitempty"`
+
+	// Retracted are the versions that have been retracted by the 'retract'
+	// directives in the go.mod of the module's latest version.
+	Retracted []string `json:"retracted,omitempty"`
+}
+
+// ParseTags groups a repo's tags into the modules that they version.
+//
+// Tags that are not canonical semver versions (optionally prefixed with a
+// module directory) are ignored. Modules are ordered by directory, and then
+// by major version.
+func ParseTags(tags []string) []*ModuleVersions {
+	var (
+		modules []*ModuleVersions
+		index   = make(map[[2]string]*ModuleVersions)
+	)
+	for _, tag := range tags {
+		dir, version := path.Split(tag)
+		if !semver.IsValid(version) || (semver.Canonical(version) != version) {
+			continue
+		}
+		dir = strings.TrimSuffix(dir, "/")
+		if (dir != "") && ((path.Clean(dir) != dir) || path.IsAbs(dir) ||
+			(dir == "..") || strings.HasPrefix(dir, "../")) {
+			continue
+		}
+
+		major := semver.Major(version)
+		if (major == "v0") || (major == "v1") {
+			major = ""
+		}
+		key := [2]string{dir, major}
+		mv, ok := index[key]
+		if !ok {
+			mv = &ModuleVersions{Dir: dir, Major: major}
+			index[key] = mv
+			modules = append(modules, mv)
+		}
+		mv.Versions = append(mv.Versions, version)
+	}
+
+	for _, mv := range modules {
+		sort.Slice(mv.Versions, func(i, j int) bool {
+			return semver.Compare(mv.Versions[i], mv.Versions[j]) < 0
+		})
+		mv.setLatest()
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if modules[i].Dir != modules[j].Dir {
+			return modules[i].Dir < modules[j].Dir
+		}
+		return semver.Compare(
+			majorOrV1(modules[i].Major),
+			majorOrV1(modules[j].Major),
+		) < 0
+	})
+	return modules
+}
+
+func majorOrV1(major string) string {
+	if major == "" {
+		return "v1"
+	}
+	return major
+}
+
+// Tag returns the name of the tag for the specified version of the module.
+func (mv *ModuleVersions) Tag(version string) string {
+	if mv.Dir == "" {
+		return version
+	}
+	return mv.Dir + "/" + version
+}
+
+// IsRetracted returns true if version has been retracted.
+func (mv *ModuleVersions) IsRetracted(version string) bool {
+	for _, r := range mv.Retracted {
+		if r == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Retract marks the versions that are retracted by the 'retract' directives
+// in gomod as retracted, and recomputes Latest.
+//
+// gomod should be the contents of the go.mod file at the module's latest
+// version, which is where the go command reads retractions from.
+func (mv *ModuleVersions) Retract(gomod []byte) error {
+	file, err := modfile.ParseLax("go.mod", gomod, nil)
+	if err != nil {
+		return errors.Wrap(err, "repo: parsing go.mod")
+	}
+
+	mv.Retracted = nil
+	for _, v := range mv.Versions {
+		for _, r := range file.Retract {
+			if (semver.Compare(r.Low, v) <= 0) &&
+				(semver.Compare(v, r.High) <= 0) {
+				mv.Retracted = append(mv.Retracted, v)
+				break
+			}
+		}
+	}
+	mv.setLatest()
+	return nil
+}
+
+// setLatest sets Latest (and Prerelease) to the highest version that is not
+// retracted, preferring releases over prereleases.
+func (mv *ModuleVersions) setLatest() {
+	mv.Latest, mv.Prerelease = "", false
+	for i := len(mv.Versions) - 1; i >= 0; i-- {
+		v := mv.Versions[i]
+		if mv.IsRetracted(v) {
+			continue
+		}
+		if semver.Prerelease(v) == "" {
+			mv.Latest, mv.Prerelease = v, false
+			return
+		}
+		if mv.Latest == "" {
+			mv.Latest, mv.Prerelease = v, true
+		}
+	}
+}
+
+// equal returns true if mv and other describe the same versions.
+func (mv *ModuleVersions) equal(other *ModuleVersions) bool {
+	return (mv.Dir == other.Dir) &&
+		(mv.Major == other.Major) &&
+		(mv.Latest == other.Latest) &&
+		stringsEqual(mv.Versions, other.Versions) &&
+		stringsEqual(mv.Retracted, other.Retracted)
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// modulesJSON formats modules for comparison and error messages.
+func modulesJSON(t *testing.T, modules []*ModuleVersions) string {
+	data, err := json.Marshal(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseTags(t *testing.T) {
+	cases := []struct {
+		name string
+		tags []string
+		want []*ModuleVersions
+	}{
+		{
+			name: "none",
+			tags: nil,
+			want: nil,
+		},
+		{
+			name: "root",
+			tags: []string{"v1.0.0", "v0.9.0", "v1.10.0", "v1.2.0"},
+			want: []*ModuleVersions{{
+				Versions: []string{"v0.9.0", "v1.0.0", "v1.2.0", "v1.10.0"},
+				Latest:   "v1.10.0",
+			}},
+		},
+		{
+			name: "non-canonical",
+			tags: []string{"v1", "v1.2", "1.2.3", "v1.2.3+build", "release",
+				"v01.2.3", "v1.0.0"},
+			want: []*ModuleVersions{{
+				Versions: []string{"v1.0.0"},
+				Latest:   "v1.0.0",
+			}},
+		},
+		{
+			name: "prefixed",
+			tags: []string{"sub/v1.0.0", "a/b/v0.1.0", "v1.1.0", "/abs/v1.0.0",
+				"../up/v1.0.0", "sub//v1.0.0", "sub/v1.0.1"},
+			want: []*ModuleVersions{
+				{Versions: []string{"v1.1.0"}, Latest: "v1.1.0"},
+				{
+					Dir:      "a/b",
+					Versions: []string{"v0.1.0"},
+					Latest:   "v0.1.0",
+				},
+				{
+					Dir:      "sub",
+					Versions: []string{"v1.0.0", "v1.0.1"},
+					Latest:   "v1.0.1",
+				},
+			},
+		},
+		{
+			name: "majors",
+			tags: []string{"v10.0.0", "v2.1.0", "v1.0.0", "v2.0.0", "v0.1.0",
+				"sub/v2.0.0"},
+			want: []*ModuleVersions{
+				{Versions: []string{"v0.1.0", "v1.0.0"}, Latest: "v1.0.0"},
+				{
+					Major:    "v2",
+					Versions: []string{"v2.0.0", "v2.1.0"},
+					Latest:   "v2.1.0",
+				},
+				{Major: "v10", Versions: []string{"v10.0.0"}, Latest: "v10.0.0"},
+				{
+					Dir:      "sub",
+					Major:    "v2",
+					Versions: []string{"v2.0.0"},
+					Latest:   "v2.0.0",
+				},
+			},
+		},
+		{
+			name: "prereleases",
+			tags: []string{"v1.1.0-rc.1", "v1.0.0", "v1.1.0-beta"},
+			want: []*ModuleVersions{{
+				Versions: []string{"v1.0.0", "v1.1.0-beta", "v1.1.0-rc.1"},
+				Latest:   "v1.0.0",
+			}},
+		},
+		{
+			name: "only prereleases",
+			tags: []string{"v2.0.0-alpha", "v2.0.0-beta"},
+			want: []*ModuleVersions{{
+				Major:      "v2",
+				Versions:   []string{"v2.0.0-alpha", "v2.0.0-beta"},
+				Latest:     "v2.0.0-beta",
+				Prerelease: true,
+			}},
+		},
+	}
+	for _, c := range cases {
+		got, want := modulesJSON(t, ParseTags(c.tags)), modulesJSON(t, c.want)
+		if got != want {
+			t.Errorf("%s:\n\texpected %s\n\tgot      %s", c.name, want, got)
+		}
+	}
+}
+
+func TestRetract(t *testing.T) {
+	versions := []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0-rc.1"}
+	cases := []struct {
+		name  string
+		gomod string
+		want  ModuleVersions // only Retracted, Latest, and Prerelease
+	}{
+		{
+			name:  "none",
+			gomod: "module example.com/mod\n",
+			want:  ModuleVersions{Latest: "v1.2.0"},
+		},
+		{
+			name:  "single",
+			gomod: "module example.com/mod\n\nretract v1.2.0\n",
+			want: ModuleVersions{
+				Retracted: []string{"v1.2.0"},
+				Latest:    "v1.1.0",
+			},
+		},
+		{
+			name: "range",
+			gomod: "module example.com/mod\n\n" +
+				"retract [v1.1.0, v1.2.0] // broken\n",
+			want: ModuleVersions{
+				Retracted: []string{"v1.1.0", "v1.2.0"},
+				Latest:    "v1.0.0",
+			},
+		},
+		{
+			name: "block",
+			gomod: "module example.com/mod\n\n" +
+				"retract (\n\tv1.0.0\n\t[v1.1.0, v1.2.0]\n)\n",
+			want: ModuleVersions{
+				Retracted:  []string{"v1.0.0", "v1.1.0", "v1.2.0"},
+				Latest:     "v1.3.0-rc.1",
+				Prerelease: true,
+			},
+		},
+		{
+			name:  "all",
+			gomod: "module example.com/mod\n\nretract [v0.0.0, v1.9.9]\n",
+			want: ModuleVersions{
+				Retracted: versions,
+			},
+		},
+		{
+			name:  "unknown versions",
+			gomod: "module example.com/mod\n\nretract v1.5.0\n",
+			want:  ModuleVersions{Latest: "v1.2.0"},
+		},
+	}
+	for _, c := range cases {
+		mv := ModuleVersions{Versions: versions}
+		if err := mv.Retract([]byte(c.gomod)); err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if !stringsEqual(mv.Retracted, c.want.Retracted) ||
+			(mv.Latest != c.want.Latest) ||
+			(mv.Prerelease != c.want.Prerelease) {
+			t.Errorf("%s: expected (%v, %q, %t), got (%v, %q, %t)", c.name,
+				c.want.Retracted, c.want.Latest, c.want.Prerelease,
+				mv.Retracted, mv.Latest, mv.Prerelease)
+		}
+	}
+
+	// Malformed go.mod files are reported, and leave mv unchanged.
+	mv := ModuleVersions{Versions: versions, Latest: "v1.2.0"}
+	if err := mv.Retract([]byte("retract (")); err == nil {
+		t.Error("expected error for malformed go.mod")
+	}
+	if mv.Latest != "v1.2.0" {
+		t.Errorf("expected Latest to be unchanged, got %q", mv.Latest)
+	}
+}
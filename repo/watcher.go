@@ -1,15 +1,22 @@
 package repo
 
 import (
+	stderrs "errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/sirupsen/logrus"
 	"go.stevenxie.me/api/pkg/stream"
 	"go.stevenxie.me/api/pkg/zero"
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrNotListed is returned by Watcher lookups when the repos have never been
+// listed successfully, so that nothing is known about them.
+var ErrNotListed = stderrs.New("repo: repos have not been listed")
+
 // NewWatcher creates a new Watcher, which keeps an updated list of
 // Go repositories, and checks for updates at regular intervals.
 func NewWatcher(
@@ -31,8 +38,11 @@ func NewWatcher(
 			},
 			interval,
 		),
-		log: cfg.Logger,
+		log:    cfg.Logger,
+		source: cfg.Source,
+		subs:   make(map[int]func(Event)),
 	}
+	w.snapshot.Store(emptySnapshot())
 	go w.run()
 	return w
 }
@@ -40,49 +50,127 @@ func NewWatcher(
 type (
 	// A Watcher caches and updates a list of repos at regular intervals.
 	// It is safe for concurrent use.
+	//
+	// The latest list of repos is published as an immutable Snapshot, which is
+	// swapped atomically upon each update, so that reads never block.
 	Watcher struct {
 		lister   ListerService
 		streamer stream.Streamer
 		log      logrus.FieldLogger
+		source   string
+
+		snapshot  atomic.Value // *Snapshot
+		updateMux sync.Mutex
+		refreshes singleflight.Group
+
+		subsMux    sync.Mutex
+		subs       map[int]func(Event)
+		nextSub    int
+		pending    []Event // events that have yet to be delivered
+		delivering bool    // whether or not pending is being delivered
+	}
 
-		mux   sync.Mutex
-		repos []string
-		err   error
+	// A RefreshResult describes the outcome of a Watcher refresh.
+	RefreshResult struct {
+		Diff     Diff
+		NumRepos int
+		Duration time.Duration
 	}
 
 	// A WatcherConfig configures a Watcher.
 	WatcherConfig struct {
 		Logger logrus.FieldLogger
+
+		// Source is the name of the source that the Watcher's lister lists
+		// repos from, i.e. 'github/user'.
+		Source string
 	}
 )
 
 var (
-	_ ListerService    = (*Watcher)(nil)
-	_ ValidatorService = (*Watcher)(nil)
+	_ ListerService     = (*Watcher)(nil)
+	_ ValidatorService  = (*Watcher)(nil)
+	_ RefresherService  = (*Watcher)(nil)
+	_ SnapshotService   = (*Watcher)(nil)
+	_ StatusService     = (*Watcher)(nil)
+	_ SubscriberService = (*Watcher)(nil)
 )
 
+// Snapshot returns the latest snapshot of Go repos.
+func (w *Watcher) Snapshot() *Snapshot {
+	return w.snapshot.Load().(*Snapshot)
+}
+
 // ListGoRepos returns the last seen list of Go repos.
-func (w *Watcher) ListGoRepos() ([]string, error) {
-	w.mux.Lock()
-	defer w.mux.Unlock()
-	repos := make([]string, len(w.repos))
-	copy(repos, w.repos)
-	return repos, w.err
+func (w *Watcher) ListGoRepos() ([]*Repo, error) {
+	snap := w.Snapshot()
+	return snap.Repos(), snap.Err()
 }
 
 // IsRepoValid returns true if repo is found in the list of Go repos, and false
-// otherwise.
+// otherwise. The check is case-insensitive.
+//
+// If the latest listing failed, the last successful listing is used instead.
+// If the repos have never been listed successfully, the error is marked with
+// ErrNotListed.
 func (w *Watcher) IsRepoValid(repo string) (bool, error) {
-	repos, err := w.ListGoRepos()
+	snap, err := w.listedSnapshot()
 	if err != nil {
-		return false, errors.Wrap(err, "repo: listing Go repos")
+		return false, err
 	}
-	for _, r := range repos {
-		if r == repo {
-			return true, nil
-		}
+	return snap.Contains(repo), nil
+}
+
+// LookupRepo returns the Repo named name, and true if it is found in the list
+// of Go repos. The lookup is case-insensitive.
+//
+// Like IsRepoValid, it falls back to the last successful listing.
+func (w *Watcher) LookupRepo(name string) (*Repo, bool, error) {
+	snap, err := w.listedSnapshot()
+	if err != nil {
+		return nil, false, err
+	}
+	r, ok := snap.Lookup(name)
+	return r, ok, nil
+}
+
+// listedSnapshot returns the latest snapshot, unless it has an error and the
+// repos have never been listed successfully.
+//
+// Since a failed listing keeps the previous list of repos, a snapshot with an
+// error still holds the last successful listing (if any).
+func (w *Watcher) listedSnapshot() (*Snapshot, error) {
+	snap := w.Snapshot()
+	if err := snap.Err(); (err != nil) && snap.ListedAt().IsZero() {
+		return nil, errors.Mark(
+			errors.Wrap(err, "repo: listing Go repos"),
+			ErrNotListed,
+		)
+	}
+	return snap, nil
+}
+
+// Subscribe registers fn to be called with an Event whenever the list of
+// repos changes. It returns a function that cancels the subscription.
+//
+// Events are delivered to subscribers one at a time and in order, without
+// holding any of the Watcher's locks, so fn may call Refresh, Subscribe, or
+// unsubscribe. Since an event may be in the midst of being delivered when fn
+// is unsubscribed, fn may be called once more after unsubscribing.
+//
+// fn should return quickly, since it delays the delivery of later events.
+func (w *Watcher) Subscribe(fn func(Event)) (unsubscribe func()) {
+	w.subsMux.Lock()
+	defer w.subsMux.Unlock()
+	id := w.nextSub
+	w.nextSub++
+	w.subs[id] = fn
+
+	return func() {
+		w.subsMux.Lock()
+		defer w.subsMux.Unlock()
+		delete(w.subs, id)
 	}
-	return false, nil
 }
 
 // DeriveRepoFullName derives the full name of a repo from a partial name.
@@ -90,11 +178,49 @@ func (w *Watcher) DeriveRepoFullName(partial string) (repo string) {
 	return w.lister.DeriveRepoFullName(partial)
 }
 
+// DerivePartialName derives the partial name of a repo from its full name.
+func (w *Watcher) DerivePartialName(repo string) (partial string) {
+	return w.lister.DerivePartialName(repo)
+}
+
+// Refresh immediately relists the Go repos, and updates the Watcher with the
+// results.
+//
+// Concurrent calls to Refresh are coalesced into a single listing, whose
+// result is shared between all callers.
+func (w *Watcher) Refresh() (*RefreshResult, error) {
+	// Deliver events after the refresh completes, so that subscribers can
+	// trigger refreshes of their own.
+	defer w.deliver()
+
+	v, err, _ := w.refreshes.Do("", func() (interface{}, error) {
+		w.log.Info("Refreshing repo list.")
+		start := time.Now()
+		repos, err := w.lister.ListGoRepos()
+		if err != nil {
+			w.log.WithError(err).Error("Failed to list latest Go repos.")
+		}
+		diff := w.update(repos, err)
+		if err != nil {
+			return nil, errors.Wrap(err, "repo: listing Go repos")
+		}
+		return &RefreshResult{
+			Diff:     diff,
+			NumRepos: len(repos),
+			Duration: time.Since(start),
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*RefreshResult), nil
+}
+
 func (w *Watcher) run() {
 	w.log.Info("Watching for repo list changes.")
 	for result := range w.streamer.Stream() {
 		var (
-			repos []string
+			repos []*Repo
 			err   error
 		)
 
@@ -102,7 +228,7 @@ func (w *Watcher) run() {
 		case error:
 			err = v
 			w.log.WithError(err).Error("Failed to list latest Go repos.")
-		case []string:
+		case []*Repo:
 			repos = v
 		default:
 			w.log.WithField("value", v).Error("Unexpected value from upstream.")
@@ -113,12 +239,84 @@ func (w *Watcher) run() {
 			"numRepos": len(repos),
 			"err":      err,
 		}).Debug("Received updated repo list.")
+		w.update(repos, err)
+		w.deliver()
+	}
+}
+
+// update stores a new snapshot containing repos and err, and queues an event
+// for subscribers if it differs from the previous snapshot. Callers must call
+// deliver afterwards, without holding any locks.
+func (w *Watcher) update(repos []*Repo, err error) Diff {
+	w.updateMux.Lock()
 
-		w.mux.Lock()
-		w.repos = repos
-		w.err = err
-		w.mux.Unlock()
+	// Keep the previous list of repos upon failure, so that transient
+	// errors are not mistaken for repo removals.
+	prev := w.Snapshot()
+	if err != nil {
+		repos = prev.repos
+	}
+	curr := NewSnapshot(repos, err)
+	if err != nil {
+		curr.listed = prev.listed
+	}
+	w.snapshot.Store(curr)
+
+	diff := DiffSnapshots(prev, curr)
+	if diff.IsEmpty() {
+		w.updateMux.Unlock()
+		return diff
+	}
+	w.log.WithFields(logrus.Fields{
+		"added":   diff.Added,
+		"removed": diff.Removed,
+		"changed": diff.Changed,
+	}).Info("Repo list changed.")
+
+	// Queue the event while holding updateMux, so that events are queued in
+	// the order that their snapshots were stored.
+	w.subsMux.Lock()
+	w.pending = append(w.pending, Event{
+		Previous: prev,
+		Current:  curr,
+		Diff:     diff,
+	})
+	w.subsMux.Unlock()
+
+	w.updateMux.Unlock()
+	return diff
+}
+
+// deliver delivers pending events to subscribers, unless they're already
+// being delivered by another call to deliver (in which case that call will
+// deliver them).
+//
+// Subscribers are called without holding subsMux, so that they may
+// subscribe, unsubscribe, or trigger updates of their own.
+func (w *Watcher) deliver() {
+	w.subsMux.Lock()
+	if w.delivering {
+		w.subsMux.Unlock()
+		return
+	}
+	w.delivering = true
+
+	for len(w.pending) > 0 {
+		event := w.pending[0]
+		w.pending = w.pending[1:]
+		subs := make([]func(Event), 0, len(w.subs))
+		for _, fn := range w.subs {
+			subs = append(subs, fn)
+		}
+
+		w.subsMux.Unlock()
+		for _, fn := range subs {
+			fn(event)
+		}
+		w.subsMux.Lock()
 	}
+	w.delivering = false
+	w.subsMux.Unlock()
 }
 
 // Stop stops the repo watch cycle.
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"go.stevenxie.me/api/pkg/zero"
+)
+
+func TestWatcherKeepsReposUponFailure(t *testing.T) {
+	w := &Watcher{log: zero.Logger(), subs: make(map[int]func(Event))}
+	w.snapshot.Store(emptySnapshot())
+	listErr := errors.New("rate limited")
+
+	// Before any successful listing, failures are reported.
+	w.update(nil, listErr)
+	if _, err := w.IsRepoValid("user/repo"); !errors.Is(err, ErrNotListed) {
+		t.Fatalf("expected ErrNotListed, got: %v", err)
+	}
+	if _, _, err := w.LookupRepo("user/repo"); !errors.Is(err, ErrNotListed) {
+		t.Fatalf("expected ErrNotListed, got: %v", err)
+	}
+
+	// After a successful listing, failures fall back to the listed repos.
+	w.update([]*Repo{{Name: "user/repo"}}, nil)
+	w.update(nil, listErr)
+	if ok, err := w.IsRepoValid("User/Repo"); (err != nil) || !ok {
+		t.Errorf("IsRepoValid: expected (true, nil), got (%t, %v)", ok, err)
+	}
+	if ok, err := w.IsRepoValid("user/other"); (err != nil) || ok {
+		t.Errorf("IsRepoValid: expected (false, nil), got (%t, %v)", ok, err)
+	}
+	r, ok, err := w.LookupRepo("user/repo")
+	if (err != nil) || !ok || (r.Name != "user/repo") {
+		t.Errorf("LookupRepo: expected 'user/repo', got (%v, %t, %v)", r, ok, err)
+	}
+}
+
+// seqLister lists a new repo upon each listing.
+type seqLister struct{ n int }
+
+func (sl *seqLister) ListGoRepos() ([]*Repo, error) {
+	sl.n++
+	return []*Repo{{Name: fmt.Sprintf("user/repo-%d", sl.n)}}, nil
+}
+
+func (*seqLister) DeriveRepoFullName(partial string) string { return partial }
+func (*seqLister) DerivePartialName(repo string) string     { return repo }
+
+func TestWatcherReentrantSubscriber(t *testing.T) {
+	w := &Watcher{
+		lister: new(seqLister),
+		log:    zero.Logger(),
+		subs:   make(map[int]func(Event)),
+	}
+	w.snapshot.Store(emptySnapshot())
+
+	var (
+		first, second []Event
+		unsubscribe   func()
+	)
+	unsubscribe = w.Subscribe(func(event Event) {
+		first = append(first, event)
+		if len(first) > 1 {
+			return
+		}
+		unsubscribe()
+		w.Subscribe(func(event Event) { second = append(second, event) })
+		if _, err := w.Refresh(); err != nil {
+			t.Errorf("refreshing from subscriber: %v", err)
+		}
+	})
+
+	done := make(chan zero.Struct)
+	go func() {
+		defer close(done)
+		if _, err := w.Refresh(); err != nil {
+			t.Errorf("refreshing: %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlocked while delivering events")
+	}
+
+	if len(first) != 1 {
+		t.Errorf("expected first subscriber to receive 1 event, got %d",
+			len(first))
+	}
+	if len(second) != 1 {
+		t.Fatalf("expected second subscriber to receive 1 event, got %d",
+			len(second))
+	}
+	if added := second[0].Diff.Added; (len(added) != 1) ||
+		(added[0] != "user/repo-2") {
+		t.Errorf("expected second event to add 'user/repo-2', got %v", added)
+	}
+}
+
+// staticLister always lists the same repos.
+type staticLister []*Repo
+
+func (sl staticLister) ListGoRepos() ([]*Repo, error) { return sl, nil }
+
+func (staticLister) DeriveRepoFullName(partial string) string { return partial }
+func (staticLister) DerivePartialName(repo string) string     { return repo }
+
+// BenchmarkWatcherIsRepoValid measures repo lookups under parallel load. It
+// only uses the Watcher's exported API, so that it can also be run against
+// earlier versions of the Watcher for comparison.
+func BenchmarkWatcherIsRepoValid(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			repos := make(staticLister, n)
+			for i := range repos {
+				repos[i] = &Repo{Name: fmt.Sprintf("user/repo-%d", i)}
+			}
+			target := repos[n-1].Name // the worst case for a linear scan
+
+			w := NewWatcher(repos, time.Hour)
+			defer w.Stop()
+			for {
+				if ok, _ := w.IsRepoValid(target); ok {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if ok, err := w.IsRepoValid(target); !ok {
+						b.Errorf("repo not found (error: %v)", err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"go.stevenxie.me/vaingogh/internal/info"
+	"go.stevenxie.me/vaingogh/repo"
+)
+
+// NewNotifier creates a new Notifier that POSTs repo change events to url.
+func NewNotifier(url string, opts ...func(*NotifierConfig)) *Notifier {
+	cfg := NotifierConfig{
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Notifier{
+		client: cfg.HTTPClient,
+		url:    url,
+		host:   cfg.Host,
+		source: cfg.Source,
+	}
+}
+
+type (
+	// A Notifier notifies a webhook endpoint of changes to the list of repos,
+	// by sending it a JSON-encoded Payload in a POST request.
+	Notifier struct {
+		client *http.Client
+		url    string
+		host   string
+		source string
+	}
+
+	// A NotifierConfig configures a Notifier.
+	NotifierConfig struct {
+		HTTPClient *http.Client
+
+		// Host and Source are reported in each Payload, so that endpoints that
+		// are notified by several servers (or hosts) can tell them apart.
+		Host   string
+		Source string
+	}
+
+	// A Payload is the request body sent to a webhook endpoint.
+	Payload struct {
+		// Host is the vanity domain whose repos changed, and Source is the
+		// source that they were listed from (i.e. 'github/username').
+		Host   string `json:"host,omitempty"`
+		Source string `json:"source,omitempty"`
+
+		repo.Diff
+		NumRepos int       `json:"numRepos"`
+		Time     time.Time `json:"time"`
+	}
+)
+
+var _ repo.NotifierService = (*Notifier)(nil)
+
+// Notify sends event to the webhook endpoint.
+func (n *Notifier) Notify(event repo.Event) error {
+	body, err := json.Marshal(Payload{
+		Host:     n.host,
+		Source:   n.source,
+		Diff:     event.Diff,
+		NumRepos: event.Current.Len(),
+		Time:     event.Current.Time(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "webhook: encoding payload")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "webhook: creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", info.Namespace+"/"+info.Version)
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "webhook: sending request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Newf("webhook: bad response status '%s'", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// An AccessLogConfig configures the access log of a Server.
+type AccessLogConfig struct {
+	// Logger is the logger that access log entries are written to.
+	Logger logrus.FieldLogger
+
+	// SampleRate is the fraction of requests that are logged, between 0 and 1.
+	// Responses with a 5xx status are always logged. If zero, every request is
+	// logged.
+	SampleRate float64
+
+	// TrustedProxies are the networks of reverse proxies that are trusted to
+	// set the X-Forwarded-For header. Requests from other addresses are
+	// attributed to their remote address.
+	TrustedProxies []*net.IPNet
+}
+
+// ParseTrustedProxies parses a list of IP addresses and CIDR ranges into a
+// list of networks, for use as AccessLogConfig.TrustedProxies.
+func ParseTrustedProxies(addrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(addrs))
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "/") {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return nil, errors.Newf("server: invalid IP address '%s'", addr)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "server: parsing CIDR '%s'", addr)
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
+
+// logAccess writes an access log entry for a request that was responded to
+// by rr after duration d, subject to sampling.
+func (srv *Server) logAccess(
+	r *http.Request,
+	rr *responseRecorder,
+	d time.Duration,
+) {
+	cfg := srv.accessLog
+	if (cfg.SampleRate > 0) && (cfg.SampleRate < 1) && (rr.status < 500) &&
+		(rand.Float64() >= cfg.SampleRate) {
+		return
+	}
+	cfg.Logger.WithFields(logrus.Fields{
+		"method":    r.Method,
+		"path":      r.URL.Path,
+		"module":    rr.module,
+		"goGet":     r.URL.Query().Get("go-get") == "1",
+		"status":    rr.status,
+		"bytes":     rr.bytes,
+		"duration":  d.String(),
+		"userAgent": r.UserAgent(),
+		"remoteIP":  clientIP(r, cfg.TrustedProxies),
+		"requestID": requestID(r),
+	}).Info("Handled request.")
+}
+
+// clientIP determines the IP address of the client that made r.
+//
+// If r was made by a trusted proxy, the X-Forwarded-For header is walked from
+// right to left, and the first address that doesn't belong to a trusted proxy
+// is used. Since hops to the left of a malformed hop can't be attributed to
+// anyone, the walk stops at malformed hops, and the nearest trusted address is
+// used instead.
+func clientIP(r *http.Request, trusted []*net.IPNet) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !isTrusted(remote, trusted) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header["X-Forwarded-For"] {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHop(hops[i])
+		if ip == "" {
+			break
+		}
+		if !isTrusted(ip, trusted) {
+			return ip
+		}
+		remote = ip
+	}
+	return remote
+}
+
+// parseHop parses an X-Forwarded-For hop, which may include a port, into an
+// IP address. It returns "" if hop is malformed.
+func parseHop(hop string) string {
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		hop = host
+	}
+	ip := net.ParseIP(hop)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+func isTrusted(addr string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipnet := range trusted {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1",
+		"fd00::/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{
+			name:   "untrusted remote",
+			remote: "203.0.113.1:1234",
+			xff:    []string{"198.51.100.1"},
+			want:   "203.0.113.1",
+		},
+		{
+			name:   "trusted remote without header",
+			remote: "10.0.0.1:1234",
+			want:   "10.0.0.1",
+		},
+		{
+			name:   "trusted remote",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"198.51.100.1"},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "trusted chain",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"198.51.100.1, 192.168.1.1, 10.0.0.2"},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "spoofed hops left of the client",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"10.0.0.3, 1.1.1.1, 198.51.100.1, 10.0.0.2"},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "multiple headers",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"198.51.100.1", "192.168.1.1"},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "all trusted",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"10.0.0.3, 10.0.0.2"},
+			want:   "10.0.0.3",
+		},
+		{
+			name:   "empty entries",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"", " , 198.51.100.1,, ", ""},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "malformed client",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"<script>, 10.0.0.2"},
+			want:   "10.0.0.2",
+		},
+		{
+			name:   "malformed hop",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"198.51.100.1, not-an-ip"},
+			want:   "10.0.0.1",
+		},
+		{
+			name:   "hops with ports",
+			remote: "10.0.0.1:1234",
+			xff:    []string{"198.51.100.1:5678, 10.0.0.2:80"},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "IPv6",
+			remote: "[fd00::1]:1234",
+			xff:    []string{"2001:db8::1, [fd00::2]:80"},
+			want:   "2001:db8::1",
+		},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remote
+		for _, v := range c.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := clientIP(r, trusted); got != c.want {
+			t.Errorf("%s: expected '%s', got '%s'", c.name, c.want, got)
+		}
+	}
+
+	// Without trusted proxies, the header is ignored.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := clientIP(r, nil); got != "10.0.0.1" {
+		t.Errorf("no trusted proxies: expected '10.0.0.1', got '%s'", got)
+	}
+}
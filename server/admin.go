@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// authorized returns true if r carries srv.adminToken as a bearer token.
+func (srv *Server) authorized(r *http.Request) bool {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	token := strings.TrimPrefix(auth, prefix)
+	return subtle.ConstantTimeCompare([]byte(token), []byte(srv.adminToken)) == 1
+}
+
+func (srv *Server) refreshHandler(log logrus.FieldLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed,
+				newErrorResponse(r, "method not allowed"))
+			return
+		}
+		if !srv.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeJSON(w, http.StatusUnauthorized,
+				newErrorResponse(r, "unauthorized"))
+			return
+		}
+
+		// Only refresh the repos of the requested host, whose refresher is
+		// unrelated to those of other hosts.
+		s := srv.siteFor(r.Host)
+		if (s == nil) || (s.refresher == nil) {
+			writeJSON(w, http.StatusNotFound,
+				newErrorResponse(r, "no repos are refreshed on this host"))
+			return
+		}
+		res, err := s.refresher.Refresh()
+		if err != nil {
+			log.WithError(err).Error("Failed to refresh repos.")
+			writeJSON(w, http.StatusBadGateway,
+				newErrorResponse(r, "failed to refresh repos"))
+			return
+		}
+		if err = writeJSON(w, http.StatusOK, struct {
+			Added    []string `json:"added"`
+			Removed  []string `json:"removed"`
+			Changed  []string `json:"changed"`
+			NumRepos int      `json:"numRepos"`
+			Duration string   `json:"duration"`
+		}{
+			Added:    res.Diff.Added,
+			Removed:  res.Diff.Removed,
+			Changed:  res.Diff.Changed,
+			NumRepos: res.NumRepos,
+			Duration: res.Duration.String(),
+		}); err != nil {
+			log.WithError(err).Error("Failed to encode refresh response.")
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/server/resolve"
+)
+
+// apiPrefix is the path prefix for the JSON API.
+const apiPrefix = "/-/api/v1/"
+
+type (
+	apiModule struct {
+		Name       string     `json:"name"`
+		ImportPath string     `json:"importPath"`
+		Repo       *repo.Repo `json:"repo"`
+	}
+
+	apiImport struct {
+		ImportPath string    `json:"importPath"`
+		ModulePath string    `json:"modulePath"`
+		Subpath    string    `json:"subpath,omitempty"`
+		Module     apiModule `json:"module"`
+
+		// Versions describes the tagged versions of the module that contains
+		// the requested package, if known.
+		Versions *repo.ModuleVersions `json:"versions,omitempty"`
+	}
+
+	apiStatus struct {
+		infoResponse
+		Sources []repo.Status `json:"sources"`
+	}
+)
+
+// apiHandler serves the JSON API.
+func (srv *Server) apiHandler(log logrus.FieldLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet) && (r.Method != http.MethodHead) {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed,
+				newErrorResponse(r, "method not allowed"))
+			return
+		}
+
+		var (
+			path   = strings.TrimPrefix(r.URL.Path, apiPrefix)
+			status = http.StatusOK
+			v      interface{}
+		)
+		s := srv.siteFor(r.Host)
+		switch {
+		case s == nil:
+			status = http.StatusNotFound
+			v = newErrorResponse(r, "no modules are served on this host")
+		case path == "status":
+			v = s.apiStatus()
+		case (path == "modules") || strings.HasPrefix(path, "modules/"):
+			if path == "modules" {
+				v = s.apiModules()
+				break
+			}
+			mod, ok := s.apiModule(strings.TrimPrefix(path, "modules/"))
+			if ok {
+				v = mod
+			} else {
+				status = http.StatusNotFound
+				v = newErrorResponse(r, "module not found")
+			}
+		default:
+			status = http.StatusNotFound
+			v = newErrorResponse(r, "not found")
+		}
+		if err := writeJSON(w, status, v); err != nil {
+			log.WithError(err).Error("Failed to encode API response.")
+		}
+	}
+}
+
+// apiStatus reports on the sources of s, but not on those of other sites
+// (whose configuration may belong to someone else).
+func (s *site) apiStatus() apiStatus {
+	status := apiStatus{
+		infoResponse: buildInfo(),
+		Sources:      make([]repo.Status, len(s.sources)),
+	}
+	for i, source := range s.sources {
+		status.Sources[i] = source.Status()
+	}
+	return status
+}
+
+func (s *site) apiModules() []apiModule {
+	if s.snapshots == nil {
+		return []apiModule{}
+	}
+	repos := s.snapshots.Snapshot().Repos()
+	mods := make([]apiModule, len(repos))
+	for i, r := range repos {
+		mods[i] = s.buildAPIModule(r)
+	}
+	sort.Slice(mods, func(i, j int) bool {
+		return strings.ToLower(mods[i].Name) < strings.ToLower(mods[j].Name)
+	})
+	return mods
+}
+
+func (s *site) apiModule(name string) (mod apiModule, ok bool) {
+	if s.snapshots == nil {
+		return apiModule{}, false
+	}
+	r, ok := s.snapshots.Snapshot().Lookup(
+		s.validator.DeriveRepoFullName(strings.Trim(name, "/")),
+	)
+	if !ok {
+		return apiModule{}, false
+	}
+	return s.buildAPIModule(r), true
+}
+
+func (s *site) buildAPIModule(r *repo.Repo) apiModule {
+	name := s.validator.DerivePartialName(r.Name)
+	return apiModule{
+		Name:       name,
+		ImportPath: s.resolver.Root(name),
+		Repo:       r,
+	}
+}
+
+func (s *site) buildAPIImport(res *resolve.Result) apiImport {
+	mod := s.buildAPIModule(res.Metadata)
+	mod.Name = res.Module
+	mod.ImportPath = res.Root
+	return apiImport{
+		ImportPath: res.ImportPath,
+		ModulePath: res.ModulePath(),
+		Subpath:    res.Subpath,
+		Module:     mod,
+		Versions:   res.Versions(),
+	}
+}
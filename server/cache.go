@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// maxCachedPages is the maximum number of pages that a pageCache holds, so
+// that requests for arbitrary package paths can't exhaust memory.
+const maxCachedPages = 10000
+
+type (
+	// A pageCache caches generated pages until it is cleared. It is safe for
+	// concurrent use.
+	pageCache struct {
+		mux   sync.RWMutex
+		pages map[pageKey]*page
+		gen   int // incremented whenever the cache is cleared
+	}
+
+	pageKey struct {
+		kind    string // i.e. 'index', 'go-get', or 'landing'
+		address string
+	}
+
+	// A page is a generated HTML page.
+	page struct {
+		html     string
+		etag     string
+		modified time.Time // zero if unknown
+	}
+)
+
+func newPageCache() *pageCache {
+	return &pageCache{pages: make(map[pageKey]*page)}
+}
+
+// get returns the page cached under key, if any, along with the current
+// generation of the cache.
+func (pc *pageCache) get(key pageKey) (p *page, ok bool, gen int) {
+	pc.mux.RLock()
+	defer pc.mux.RUnlock()
+	p, ok = pc.pages[key]
+	return p, ok, pc.gen
+}
+
+// put caches p under key, unless the cache has been cleared since generation
+// gen (in which case p may be stale).
+func (pc *pageCache) put(key pageKey, p *page, gen int) {
+	pc.mux.Lock()
+	defer pc.mux.Unlock()
+	if (gen == pc.gen) && (len(pc.pages) < maxCachedPages) {
+		pc.pages[key] = p
+	}
+}
+
+// clear removes all pages from the cache.
+func (pc *pageCache) clear() {
+	pc.mux.Lock()
+	defer pc.mux.Unlock()
+	pc.pages = make(map[pageKey]*page)
+	pc.gen++
+}
+
+func newPage(html string, modified time.Time) *page {
+	sum := sha256.Sum256([]byte(html))
+	return &page{
+		html:     html,
+		etag:     fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:16])),
+		modified: modified,
+	}
+}
+
+// renderPage returns the page identified by key from the page cache of s, or
+// generates it using gen (and caches it) if it isn't cached.
+func (s *site) renderPage(
+	key pageKey,
+	gen func() (html string, err error),
+) (*page, error) {
+	if s.pages == nil {
+		html, err := gen()
+		if err != nil {
+			return nil, err
+		}
+		return newPage(html, time.Time{}), nil
+	}
+
+	p, ok, cachegen := s.pages.get(key)
+	if ok {
+		return p, nil
+	}
+	html, err := gen()
+	if err != nil {
+		return nil, err
+	}
+	p = newPage(html, time.Now())
+	s.pages.put(key, p, cachegen)
+	return p, nil
+}
+
+// servePage responds to r with p, or with 304 Not Modified if the client's
+// copy of p is up to date.
+func (srv *Server) servePage(w http.ResponseWriter, r *http.Request, p *page) {
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=UTF-8")
+	h.Set("ETag", p.etag)
+	if srv.cacheMaxAge > 0 {
+		h.Set("Cache-Control",
+			fmt.Sprintf("public, max-age=%d", int(srv.cacheMaxAge/time.Second)))
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(w, r, "", p.modified, strings.NewReader(p.html))
+}
+
+// A pageWriter serves an uncached HTML page that is written to it, like
+// servePage (so that the page gets an ETag, and conditional requests are
+// handled the same way as for cached pages).
+//
+// The page must be written in full using a single call to Write, as
+// template.Generator.WriteHTML does. Until then, the response can still be
+// used to report errors.
+type pageWriter struct {
+	srv     *Server
+	w       http.ResponseWriter
+	r       *http.Request
+	written bool
+}
+
+func (pw *pageWriter) Write(p []byte) (n int, err error) {
+	if pw.written {
+		return 0, errors.New("server: page was already written")
+	}
+	pw.written = true
+	pw.srv.servePage(pw.w, pw.r, newPage(string(p), time.Time{}))
+	return len(p), nil
+}
+
+// ClearCache removes all cached pages, i.e. after the templates that they were
+// generated from have changed. It is a no-op if page caching is disabled.
+func (srv *Server) ClearCache() {
+	for _, s := range srv.sites {
+		if s.pages != nil {
+			s.pages.clear()
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPageWriterMatchesServePage(t *testing.T) {
+	const html = "<html>page</html>"
+	srv := &Server{cacheMaxAge: time.Minute}
+
+	serve := func(r *http.Request, uncached bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		if uncached {
+			pw := &pageWriter{srv: srv, w: w, r: r}
+			if _, err := pw.Write([]byte(html)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pw.Write([]byte(html)); err == nil {
+				t.Error("expected error upon writing a page twice")
+			}
+		} else {
+			srv.servePage(w, r, newPage(html, time.Now()))
+		}
+		return w
+	}
+
+	var etags [2]string
+	for i, uncached := range []bool{false, true} {
+		w := serve(httptest.NewRequest(http.MethodGet, "/", nil), uncached)
+		if (w.Code != http.StatusOK) || (w.Body.String() != html) {
+			t.Errorf("uncached=%t: expected page, got %d: %q", uncached, w.Code,
+				w.Body)
+		}
+		for _, key := range []string{"ETag", "Content-Type", "Cache-Control"} {
+			if w.Header().Get(key) == "" {
+				t.Errorf("uncached=%t: expected %s header", uncached, key)
+			}
+		}
+		etags[i] = w.Header().Get("ETag")
+
+		// Conditional requests are answered with 304 Not Modified.
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-None-Match", etags[i])
+		if w = serve(r, uncached); w.Code != http.StatusNotModified {
+			t.Errorf("uncached=%t: expected 304 for a matching ETag, got %d",
+				uncached, w.Code)
+		}
+	}
+	if etags[0] != etags[1] {
+		t.Errorf("expected the same ETag in both paths, got %q and %q",
+			etags[0], etags[1])
+	}
+}
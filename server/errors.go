@@ -0,0 +1,98 @@
+package server
+
+import (
+	stderrs "errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/server/resolve"
+	"go.stevenxie.me/vaingogh/template"
+)
+
+// errNoData is returned by handlers when the server's repos haven't been
+// listed successfully yet.
+var errNoData = stderrs.New("server: repos have not been listed yet")
+
+// noDataRetryAfter is how long clients are asked to wait before retrying
+// requests that failed with errNoData or repo.ErrNotListed.
+const noDataRetryAfter = 30 * time.Second
+
+// errorStatus maps err to the status code and message that it should be
+// reported to clients with.
+//
+// Messages never contain the details of internal errors, which may expose
+// server internals (like upstream API errors) to clients.
+func errorStatus(err error) (status int, message string) {
+	switch {
+	case errors.Is(err, resolve.ErrNotFound):
+		return http.StatusNotFound, "No module exists at this path."
+	case errors.Is(err, errUnknownHost):
+		return http.StatusNotFound, "No modules are served on this host."
+	case errors.Is(err, errNoData), errors.Is(err, repo.ErrNotListed):
+		return http.StatusServiceUnavailable,
+			"The server is still starting up; please try again shortly."
+	default:
+		return http.StatusInternalServerError,
+			"Something went wrong while handling this request."
+	}
+}
+
+// writeError responds to r with an error page (or a JSON error, if the client
+// prefers JSON) that describes err.
+func (srv *Server) writeError(
+	w http.ResponseWriter,
+	r *http.Request,
+	log logrus.FieldLogger,
+	err error,
+) {
+	status, message := errorStatus(err)
+	if status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After",
+			strconv.Itoa(int(noDataRetryAfter/time.Second)))
+	}
+
+	id := requestID(r)
+	if prefersJSON(r) {
+		if err := writeJSON(w, status, errorResponse{
+			Error:     message,
+			RequestID: id,
+		}); err != nil {
+			log.WithError(err).Error("Failed to encode error response.")
+		}
+		return
+	}
+
+	// Respond in plain text upon requests for unknown hosts, whose error
+	// pages shouldn't be branded as those of another host.
+	s := srv.siteFor(r.Host)
+	if s == nil {
+		writePlainError(w, status, message)
+		return
+	}
+	html, err := s.generator.GenerateErrorHTML(template.ErrorData{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+		RequestID:  id,
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to generate error page.")
+		writePlainError(w, status, message)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(status)
+	io.WriteString(w, html)
+}
+
+func writePlainError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	w.WriteHeader(status)
+	io.WriteString(w, message)
+}
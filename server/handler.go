@@ -1,79 +1,132 @@
 package server
 
 import (
-	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/sirupsen/logrus"
-	"go.stevenxie.me/vaingogh/internal/info"
-	serverinfo "go.stevenxie.me/vaingogh/server/internal/info"
+	"go.stevenxie.me/vaingogh/server/resolve"
 )
 
 func (srv *Server) handler(log logrus.FieldLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var status int
+		log := log.WithField("requestID", requestID(r))
+		w.Header().Add("Vary", "Accept")
 		if err := func() error {
-			// Respond with server info upon root path request.
-			if r.URL.Path == "/" {
-				w.Header().Set("Content-Type", "application/json")
-				enc := json.NewEncoder(w)
-				enc.SetIndent("", "  ")
-				err := enc.Encode(struct {
-					Name        string `json:"name"`
-					Version     string `json:"version"`
-					Environment string `json:"environment,omitempty"`
-				}{
-					Name:        serverinfo.Name,
-					Version:     info.Version,
-					Environment: os.Getenv("GOENV"),
-				})
-				return errors.Wrap(err, "encoding info response")
+			s := srv.siteFor(r.Host)
+			if s == nil {
+				return errUnknownHost
 			}
 
-			// Derive addresses / repo from URL.
-			address := r.Host + r.URL.Path
-			partial := strings.TrimPrefix(address, srv.baseURL)
-			partial = strings.Split(partial, "/")[1]
-			repo := srv.validator.DeriveRepoFullName(partial)
-			prefix := fmt.Sprintf("%s/%s", srv.baseURL, partial)
+			// Don't serve anything until repos have been listed, since all
+			// modules would otherwise appear not to exist.
+			if (s.snapshots != nil) && s.snapshots.Snapshot().ListedAt().IsZero() {
+				return errNoData
+			}
+
+			// Respond with the module index upon a request for the base URL.
+			//
+			// The address uses the site's own host, since r.Host may differ
+			// from it in case or port.
+			address := hostOf(s.resolver.BaseURL()) + r.URL.Path
+			if s.resolver.IsBase(address) {
+				page, err := s.renderPage(
+					pageKey{kind: "index"},
+					s.generateIndexHTML,
+				)
+				if err != nil {
+					log.WithError(err).Error("Failed to generate index page.")
+					return errors.Wrap(err, "generating index page")
+				}
+				srv.servePage(w, r, page)
+				return nil
+			}
 
-			// Ensure repository is valid.
-			valid, err := srv.validator.IsRepoValid(repo)
+			// Resolve the module that the requested import path belongs to.
+			res, err := s.resolver.Resolve(address)
 			if err != nil {
-				log.WithError(err).Error("Failure while checking repo validity.")
-				return errors.Wrap(err, "checking repo validity")
+				if !errors.Is(err, resolve.ErrNotFound) {
+					log.WithError(err).Error("Failure while resolving import path.")
+				}
+				return errors.Wrap(err, "resolving import path")
 			}
-			if !valid {
-				status = http.StatusNotFound
-				return errors.New("invalid repo")
+			setModule(w, res.Module)
+
+			// Respond with module metadata if the client prefers JSON.
+			if prefersJSON(r) {
+				if err := writeJSON(
+					w, http.StatusOK,
+					s.buildAPIImport(res),
+				); err != nil {
+					log.WithError(err).Error("Failed to write JSON response.")
+				}
+				return nil
 			}
 
-			// Generate HTML page.
-			html, err := srv.generator.GenerateHTML(prefix, address, repo)
-			if err != nil {
-				return errors.Wrap(err, "generating HTML page")
+			// Generate HTML page; 'go get' requests receive a minimal page
+			// containing only meta tags, while users receive a landing page.
+			key := pageKey{kind: "landing", address: res.ImportPath}
+			if r.URL.Query().Get("go-get") == "1" {
+				key.kind = "go-get"
 			}
 
-			// Send HTML response.
-			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
-			if _, err := io.WriteString(w, html); err != nil {
-				return errors.Wrap(err, "writing HTML response")
+			// Without a page cache, 'go get' pages (which are requested far
+			// more often than landing pages) are written to w directly.
+			if (s.pages == nil) && (key.kind == "go-get") {
+				pw := &pageWriter{srv: srv, w: w, r: r}
+				if err := s.writeGoGetHTML(pw, res); err != nil {
+					if pw.written {
+						log.WithError(err).Error("Failed to write HTML page.")
+						return nil
+					}
+					if !errors.Is(err, resolve.ErrNotFound) {
+						log.WithError(err).Error("Failed to generate HTML page.")
+					}
+					return errors.Wrap(err, "generating HTML page")
+				}
+				return nil
 			}
 
-			return nil
-		}(); err != nil { // catch error and write as text
-			w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
-			if status != 0 {
-				w.WriteHeader(status)
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
+			page, err := s.renderPage(key, func() (string, error) {
+				return s.generateModuleHTML(res, key.kind == "go-get")
+			})
+			if err != nil {
+				if !errors.Is(err, resolve.ErrNotFound) {
+					log.WithError(err).Error("Failed to generate HTML page.")
+				}
+				return errors.Wrap(err, "generating HTML page")
 			}
-			io.WriteString(w, err.Error())
+			srv.servePage(w, r, page)
+			return nil
+		}(); err != nil {
+			srv.writeError(w, r, log, err)
 		}
 	}
 }
+
+// generateModuleHTML generates a page for the module that res belongs to;
+// either a 'go get' page, or a landing page.
+func (s *site) generateModuleHTML(
+	res *resolve.Result,
+	goGet bool,
+) (html string, err error) {
+	imp, err := res.Import(s.layouts)
+	if err != nil {
+		return "", err
+	}
+	if goGet {
+		return s.generator.GenerateHTML(imp)
+	}
+	return s.generator.GenerateLandingHTML(imp)
+}
+
+// writeGoGetHTML writes the 'go get' page for the module that res belongs to
+// to w.
+func (s *site) writeGoGetHTML(w io.Writer, res *resolve.Result) error {
+	imp, err := res.Import(s.layouts)
+	if err != nil {
+		return err
+	}
+	return s.generator.WriteHTML(w, imp)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.stevenxie.me/vaingogh/repo"
+)
+
+// Default paths of the liveness and readiness endpoints.
+const (
+	DefaultLivenessPath  = "/-/healthz"
+	DefaultReadinessPath = "/-/readyz"
+)
+
+type (
+	healthResponse struct {
+		Status  string         `json:"status"`
+		Sources []sourceHealth `json:"sources,omitempty"`
+	}
+
+	sourceHealth struct {
+		repo.Status
+		Ready     bool   `json:"ready"`
+		Staleness string `json:"staleness,omitempty"`
+	}
+)
+
+// livenessHandler responds with 200 for as long as the server is able to
+// handle requests.
+func livenessHandler(log logrus.FieldLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := writeJSON(
+			w, http.StatusOK,
+			healthResponse{Status: "ok"},
+		); err != nil {
+			log.WithError(err).Error("Failed to encode liveness response.")
+		}
+	}
+}
+
+// readinessHandler responds with 200 if every source is ready, and 503
+// otherwise.
+//
+// A source is ready once it has been listed successfully, for as long as its
+// last successful listing is no older than srv.maxStaleness.
+func (srv *Server) readinessHandler(log logrus.FieldLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			res    = healthResponse{Status: "ok"}
+			status = http.StatusOK
+			now    = time.Now()
+		)
+		res.Sources = make([]sourceHealth, len(srv.sources))
+		for i, source := range srv.sources {
+			health := sourceHealth{Status: source.Status()}
+			if last := health.LastSuccess; !last.IsZero() {
+				staleness := now.Sub(last)
+				health.Staleness = staleness.Round(time.Second).String()
+				health.Ready = (srv.maxStaleness <= 0) ||
+					(staleness <= srv.maxStaleness)
+			}
+			if !health.Ready {
+				res.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+			}
+			res.Sources[i] = health
+		}
+		if err := writeJSON(w, status, res); err != nil {
+			log.WithError(err).Error("Failed to encode readiness response.")
+		}
+	}
+}
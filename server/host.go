@@ -0,0 +1,100 @@
+package server
+
+import (
+	stderrs "errors"
+	"net"
+	"strings"
+
+	"go.stevenxie.me/vaingogh/pkg/urlutil"
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/server/resolve"
+	"go.stevenxie.me/vaingogh/template"
+)
+
+// errUnknownHost is returned by handlers when a request's host isn't served
+// by the server.
+var errUnknownHost = stderrs.New("server: unknown host")
+
+type (
+	// A Host is an additional vanity domain that is served by a Server, with
+	// its own repos, pages, and index page.
+	Host struct {
+		// BaseURL is the base URL of the host's vanity imports, which may
+		// contain a path component (i.e. 'example.com/go').
+		BaseURL   string
+		Generator template.Generator
+		Validator repo.ValidatorService
+
+		// Layouts, Snapshots, Events, Sources, and Refresher are like the
+		// corresponding fields of Config, but apply only to the host.
+		Layouts   repo.LayoutService
+		Snapshots repo.SnapshotService
+		Events    repo.SubscriberService
+		Sources   []repo.StatusService
+		Refresher repo.RefresherService
+	}
+
+	// A site holds the state used to serve a single vanity domain.
+	site struct {
+		generator template.Generator
+		validator repo.ValidatorService
+		resolver  *resolve.Resolver
+		layouts   repo.LayoutService
+		snapshots repo.SnapshotService
+		sources   []repo.StatusService
+		refresher repo.RefresherService
+
+		pages       *pageCache // nil if caching is disabled
+		unsubscribe func()
+	}
+)
+
+func newSite(h *Host) *site {
+	s := &site{
+		generator: h.Generator,
+		validator: h.Validator,
+		resolver:  resolve.NewResolver(h.BaseURL, h.Validator),
+		layouts:   h.Layouts,
+		snapshots: h.Snapshots,
+		sources:   h.Sources,
+		refresher: h.Refresher,
+	}
+	if h.Events != nil {
+		s.pages = newPageCache()
+		s.unsubscribe = h.Events.Subscribe(func(repo.Event) {
+			s.pages.clear()
+		})
+	}
+	return s
+}
+
+// hostOf returns the host of a base URL or import path, i.e. 'example.com'.
+func hostOf(url string) string {
+	url = strings.TrimLeft(urlutil.StripProtocol(url), "/")
+	if i := strings.IndexByte(url, '/'); i > -1 {
+		return url[:i]
+	}
+	return url
+}
+
+// siteFor returns the site that serves host, or nil if no site serves it.
+//
+// Ports are ignored, so that requests to i.e. 'example.com:8080' are served by
+// the site for 'example.com'.
+func (srv *Server) siteFor(host string) *site {
+	host = stripPort(host)
+	for _, s := range srv.sites {
+		if strings.EqualFold(stripPort(hostOf(s.resolver.BaseURL())), host) {
+			return s
+		}
+	}
+	return nil
+}
+
+// stripPort removes the port (and IPv6 brackets) from host, if it has one.
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
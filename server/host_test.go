@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.stevenxie.me/api/pkg/zero"
+
+	"go.stevenxie.me/vaingogh/repo"
+)
+
+func TestSiteFor(t *testing.T) {
+	srv := &Server{sites: []*site{
+		newSite(&Host{BaseURL: "https://example.com/go"}),
+		newSite(&Host{BaseURL: "go.example.org"}),
+		newSite(&Host{BaseURL: "localhost:3000"}),
+	}}
+	cases := []struct {
+		host string
+		want int // the index of the expected site, or -1 for none
+	}{
+		{"example.com", 0},
+		{"Example.COM", 0},
+		{"example.com:8080", 0},
+		{"go.example.org:443", 1},
+		{"localhost", 2},
+		{"localhost:4000", 2},
+		{"other.example.com", -1},
+		{"other.example.com:80", -1},
+		{"", -1},
+	}
+	for _, c := range cases {
+		var want *site
+		if c.want > -1 {
+			want = srv.sites[c.want]
+		}
+		if got := srv.siteFor(c.host); got != want {
+			t.Errorf("siteFor(%q): expected site %d, got %v", c.host, c.want, got)
+		}
+	}
+}
+
+type (
+	testSource string
+
+	// testRefresher counts the number of times that it was refreshed.
+	testRefresher struct{ n int }
+)
+
+func (s testSource) Status() repo.Status { return repo.Status{Source: string(s)} }
+
+func (r *testRefresher) Refresh() (*repo.RefreshResult, error) {
+	r.n++
+	return new(repo.RefreshResult), nil
+}
+
+func TestSiteScopedEndpoints(t *testing.T) {
+	refreshers := []*testRefresher{new(testRefresher), new(testRefresher)}
+	srv := &Server{
+		log:           zero.Logger(),
+		adminToken:    "token",
+		livenessPath:  DefaultLivenessPath,
+		readinessPath: DefaultReadinessPath,
+		sites: []*site{
+			newSite(&Host{
+				BaseURL:   "example.com",
+				Sources:   []repo.StatusService{testSource("github/a")},
+				Refresher: refreshers[0],
+			}),
+			newSite(&Host{
+				BaseURL:   "example.org",
+				Sources:   []repo.StatusService{testSource("github/b")},
+				Refresher: refreshers[1],
+			}),
+		},
+	}
+	handler := srv.buildHandler()
+	serve := func(method, host, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.Host = host
+		r.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Each host only reports on its own sources.
+	for host, want := range map[string]string{
+		"example.com": "github/a",
+		"example.org": "github/b",
+	} {
+		w := serve(http.MethodGet, host, "/-/api/v1/status")
+		var status apiStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatalf("%s: decoding status: %v", host, err)
+		}
+		if (len(status.Sources) != 1) || (status.Sources[0].Source != want) {
+			t.Errorf("%s: expected only source '%s', got %+v", host, want,
+				status.Sources)
+		}
+	}
+
+	// Each host only refreshes its own repos.
+	if w := serve(http.MethodPost, "example.org", "/-/refresh"); w.Code !=
+		http.StatusOK {
+		t.Errorf("refresh: expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if (refreshers[0].n != 0) || (refreshers[1].n != 1) {
+		t.Errorf("refresh: expected only the second host to be refreshed, "+
+			"got counts %d and %d", refreshers[0].n, refreshers[1].n)
+	}
+
+	// Unknown hosts are served nothing.
+	for _, c := range []struct{ method, path string }{
+		{http.MethodGet, "/-/api/v1/status"},
+		{http.MethodGet, "/-/info"},
+		{http.MethodPost, "/-/refresh"},
+	} {
+		if w := serve(c.method, "other.example.com", c.path); w.Code !=
+			http.StatusNotFound {
+			t.Errorf("%s: expected status 404 on unknown host, got %d", c.path,
+				w.Code)
+		}
+	}
+	if (refreshers[0].n != 0) || (refreshers[1].n != 1) {
+		t.Error("refresh: expected no refresh upon request to unknown host")
+	}
+}
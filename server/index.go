@@ -0,0 +1,13 @@
+package server
+
+import "go.stevenxie.me/vaingogh/template"
+
+// generateIndexHTML generates an index page listing every module in the
+// latest repo snapshot of s.
+func (s *site) generateIndexHTML() (html string, err error) {
+	var imps []template.Import
+	if s.snapshots != nil {
+		imps = s.resolver.Imports(s.snapshots.Snapshot().Repos())
+	}
+	return s.generator.GenerateIndexHTML(s.resolver.BaseURL(), imps)
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"go.stevenxie.me/vaingogh/internal/info"
+	serverinfo "go.stevenxie.me/vaingogh/server/internal/info"
+)
+
+// infoHandler responds with server info, upon requests to hosts that the
+// server serves.
+func (srv *Server) infoHandler(log logrus.FieldLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if srv.siteFor(r.Host) == nil {
+			writeJSON(w, http.StatusNotFound,
+				newErrorResponse(r, "no modules are served on this host"))
+			return
+		}
+		if err := writeJSON(w, http.StatusOK, buildInfo()); err != nil {
+			log.WithError(err).Error("Failed to encode info response.")
+		}
+	}
+}
+
+type infoResponse struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Environment string `json:"environment,omitempty"`
+}
+
+func buildInfo() infoResponse {
+	return infoResponse{
+		Name:        serverinfo.Name,
+		Version:     info.Version,
+		Environment: os.Getenv("GOENV"),
+	}
+}
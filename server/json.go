@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// writeJSON writes v to w as indented JSON, with the specified status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type errorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+func newErrorResponse(r *http.Request, msg string) errorResponse {
+	return errorResponse{Error: msg, RequestID: requestID(r)}
+}
+
+// prefersJSON returns true if the Accept header of r prefers JSON over HTML.
+func prefersJSON(r *http.Request) bool {
+	var jsonq, htmlq float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediatype, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mediatype {
+		case "application/json":
+			jsonq = maxFloat(jsonq, q)
+		case "text/html", "text/*", "*/*":
+			htmlq = maxFloat(htmlq, q)
+		}
+	}
+	return jsonq > htmlq
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// A ListenConfig describes where a Server listens for connections. Exactly one
+// of Addr, Socket, and Systemd must be set.
+type ListenConfig struct {
+	// Addr is a TCP address to listen on, i.e. ':3000' or '127.0.0.1:3000'.
+	Addr string
+
+	// Socket is the path of a Unix domain socket to listen on. If SocketMode
+	// is non-zero, the socket's permissions are set to SocketMode; if
+	// SocketGroup is set, the socket's group is set to SocketGroup.
+	//
+	// A stale socket left at Socket by a previous server is removed.
+	Socket      string
+	SocketMode  os.FileMode
+	SocketGroup string
+
+	// If Systemd is set, a socket passed by systemd socket activation (see
+	// sd_listen_fds(3)) is listened on. If SystemdName is set, the socket
+	// named SystemdName (using 'FileDescriptorName=') is used; otherwise, the
+	// first socket is used.
+	Systemd     bool
+	SystemdName string
+}
+
+// Listen creates the listener described by cfg.
+func Listen(cfg ListenConfig) (net.Listener, error) {
+	var n int
+	for _, set := range []bool{cfg.Addr != "", cfg.Socket != "", cfg.Systemd} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errors.New("server: exactly one of an address, a socket, " +
+			"and systemd must be configured")
+	}
+
+	switch {
+	case cfg.Socket != "":
+		return listenUnix(cfg.Socket, cfg.SocketMode, cfg.SocketGroup)
+	case cfg.Systemd:
+		return listenSystemd(cfg.SystemdName)
+	default:
+		ln, err := net.Listen("tcp", cfg.Addr)
+		return ln, errors.Wrap(err, "server: listening on address")
+	}
+}
+
+func listenUnix(path string, mode os.FileMode, group string) (net.Listener,
+	error) {
+	// Remove stale sockets, but nothing else.
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.Newf("server: '%s' exists, and is not a socket",
+				path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, errors.Wrap(err, "server: removing stale socket")
+		}
+	}
+
+	// Create the socket in a private directory, and only move it into place
+	// once its permissions are set, so that it's never reachable with the
+	// looser permissions that it's created with.
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".socket-")
+	if err != nil {
+		return nil, errors.Wrap(err, "server: creating socket directory")
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "socket")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, errors.Wrap(err, "server: listening on socket")
+	}
+	ul := &unixListener{UnixListener: ln.(*net.UnixListener), path: path}
+	ul.SetUnlinkOnClose(false) // the socket is unlinked at path instead
+
+	if err = setSocketPerms(tmp, mode, group); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, errors.Wrap(err, "server: moving socket into place")
+	}
+	return ul, nil
+}
+
+// A unixListener is a net.UnixListener whose socket was moved to path after
+// it was created. It unlinks the socket at path when closed.
+type unixListener struct {
+	*net.UnixListener
+	path string
+	once sync.Once
+}
+
+func (ul *unixListener) Close() error {
+	err := ul.UnixListener.Close()
+	ul.once.Do(func() { os.Remove(ul.path) })
+	return err
+}
+
+func setSocketPerms(path string, mode os.FileMode, group string) error {
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			return errors.Wrap(err, "server: setting socket mode")
+		}
+	}
+	if group == "" {
+		return nil
+	}
+	grp, err := user.LookupGroup(group)
+	if err != nil {
+		return errors.Wrap(err, "server: looking up socket group")
+	}
+	gid, err := strconv.Atoi(grp.Gid)
+	if err != nil {
+		return errors.Wrap(err, "server: parsing socket group ID")
+	}
+	return errors.Wrap(os.Chown(path, -1, gid), "server: setting socket group")
+}
+
+// listenSystemdFDStart is the first file descriptor passed by systemd socket
+// activation.
+const listenSystemdFDStart = 3
+
+// newSystemdFile opens a file descriptor passed by systemd. It is a variable
+// so that tests can pass sockets of their own.
+var newSystemdFile = os.NewFile
+
+func listenSystemd(name string) (net.Listener, error) {
+	i, err := selectSystemdSocket(os.Getenv, os.Getpid(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't pass the sockets on to child processes.
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		os.Unsetenv(key)
+	}
+
+	f := newSystemdFile(uintptr(listenSystemdFDStart+i), "systemd-socket")
+	defer f.Close() // the listener uses a duplicate of the file descriptor
+	ln, err := net.FileListener(f)
+	return ln, errors.Wrap(err, "server: listening on systemd socket")
+}
+
+// selectSystemdSocket returns the index of the socket to listen on among
+// those passed by systemd to the process pid, using the environment variables
+// described by sd_listen_fds(3) (looked up using getenv).
+//
+// If name is set, the socket named name is selected; otherwise, the first
+// socket is selected.
+func selectSystemdSocket(
+	getenv func(string) string,
+	pid int,
+	name string,
+) (int, error) {
+	if lpid, err := strconv.Atoi(getenv("LISTEN_PID")); (err != nil) ||
+		(lpid != pid) {
+		return 0, errors.New("server: no sockets were passed by systemd " +
+			"(LISTEN_PID is not set to this process)")
+	}
+	nfds, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if (err != nil) || (nfds < 1) {
+		return 0, errors.New("server: no sockets were passed by systemd " +
+			"(LISTEN_FDS)")
+	}
+	if name == "" {
+		return 0, nil
+	}
+	for i, fdname := range strings.Split(getenv("LISTEN_FDNAMES"), ":") {
+		if (i < nfds) && (fdname == name) {
+			return i, nil
+		}
+	}
+	return 0, errors.Newf("server: no socket named '%s' was passed by "+
+		"systemd (LISTEN_FDNAMES)", name)
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestListenValidation(t *testing.T) {
+	for _, cfg := range []ListenConfig{
+		{},
+		{Addr: "127.0.0.1:0", Socket: "vaingogh.sock"},
+		{Addr: "127.0.0.1:0", Systemd: true},
+	} {
+		if ln, err := Listen(cfg); err == nil {
+			ln.Close()
+			t.Errorf("expected error for config %+v", cfg)
+		}
+	}
+
+	ln, err := Listen(ListenConfig{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+}
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vaingogh-listen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vaingogh.sock")
+
+	// A stale socket is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := Listen(ListenConfig{Socket: path, SocketMode: 0600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Errorf("expected a socket at '%s', got mode %s", path, info.Mode())
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected socket permissions 0600, got %#o", perm)
+	}
+
+	// The socket accepts connections once it has been moved into place.
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dialing socket: %v", err)
+	}
+	conn.Close()
+
+	// Only the socket is left in its directory, and it is removed once the
+	// listener is closed.
+	if names, err := ioutil.ReadDir(dir); (err != nil) || (len(names) != 1) {
+		t.Errorf("expected only the socket in '%s', got (%v, %v)", dir, names,
+			err)
+	}
+	ln.Close()
+	if _, err = os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("expected socket to be removed, got: %v", err)
+	}
+
+	// Files that aren't sockets are left alone.
+	if err = ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ln, err = Listen(ListenConfig{Socket: path}); err == nil {
+		ln.Close()
+		t.Error("expected error when the socket path is a regular file")
+	}
+}
+
+func TestSelectSystemdSocket(t *testing.T) {
+	const pid = 1234
+	cases := []struct {
+		name string
+		env  map[string]string
+		sock string // the name of the socket to select
+		want int    // -1 if an error is expected
+	}{
+		{
+			name: "not socket activated",
+			env:  map[string]string{},
+			want: -1,
+		},
+		{
+			name: "mismatched pid",
+			env:  map[string]string{"LISTEN_PID": "4321", "LISTEN_FDS": "1"},
+			want: -1,
+		},
+		{
+			name: "malformed pid",
+			env:  map[string]string{"LISTEN_PID": "self", "LISTEN_FDS": "1"},
+			want: -1,
+		},
+		{
+			name: "no fds",
+			env:  map[string]string{"LISTEN_PID": "1234", "LISTEN_FDS": "0"},
+			want: -1,
+		},
+		{
+			name: "malformed fds",
+			env:  map[string]string{"LISTEN_PID": "1234", "LISTEN_FDS": "x"},
+			want: -1,
+		},
+		{
+			name: "first",
+			env:  map[string]string{"LISTEN_PID": "1234", "LISTEN_FDS": "2"},
+			want: 0,
+		},
+		{
+			name: "named",
+			env: map[string]string{
+				"LISTEN_PID":     "1234",
+				"LISTEN_FDS":     "3",
+				"LISTEN_FDNAMES": "metrics:http:https",
+			},
+			sock: "https",
+			want: 2,
+		},
+		{
+			name: "unknown name",
+			env: map[string]string{
+				"LISTEN_PID":     "1234",
+				"LISTEN_FDS":     "2",
+				"LISTEN_FDNAMES": "metrics:http",
+			},
+			sock: "https",
+			want: -1,
+		},
+		{
+			name: "name beyond LISTEN_FDS",
+			env: map[string]string{
+				"LISTEN_PID":     "1234",
+				"LISTEN_FDS":     "1",
+				"LISTEN_FDNAMES": "metrics:http",
+			},
+			sock: "http",
+			want: -1,
+		},
+	}
+	for _, c := range cases {
+		getenv := func(key string) string { return c.env[key] }
+		i, err := selectSystemdSocket(getenv, pid, c.sock)
+		if c.want < 0 {
+			if err == nil {
+				t.Errorf("%s: expected error, got index %d", c.name, i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		} else if i != c.want {
+			t.Errorf("%s: expected index %d, got %d", c.name, c.want, i)
+		}
+	}
+}
+
+func TestListenSystemd(t *testing.T) {
+	// Pass a listener's file in place of one from systemd. listenSystemd
+	// takes ownership of (and closes) the file.
+	tcpln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpln.Close()
+	f, err := tcpln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(fn func(uintptr, string) *os.File) {
+		newSystemdFile = fn
+	}(newSystemdFile)
+	var fd uintptr
+	newSystemdFile = func(sfd uintptr, _ string) *os.File {
+		fd = sfd
+		return f
+	}
+
+	for key, value := range map[string]string{
+		"LISTEN_PID":     strconv.Itoa(os.Getpid()),
+		"LISTEN_FDS":     "2",
+		"LISTEN_FDNAMES": "metrics:http",
+	} {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	ln, err := Listen(ListenConfig{Systemd: true, SystemdName: "http"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if fd != listenSystemdFDStart+1 {
+		t.Errorf("expected fd %d to be used, got %d", listenSystemdFDStart+1,
+			fd)
+	}
+	if ln.Addr().String() != tcpln.Addr().String() {
+		t.Errorf("expected listener on %s, got %s", tcpln.Addr(), ln.Addr())
+	}
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		if _, ok := os.LookupEnv(key); ok {
+			t.Errorf("expected %s to be unset", key)
+		}
+	}
+}
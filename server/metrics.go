@@ -0,0 +1,4 @@
+package server
+
+// DefaultMetricsPath is the default path of the metrics endpoint.
+const DefaultMetricsPath = "/-/metrics"
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"go.stevenxie.me/vaingogh/proxy"
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/server/resolve"
+)
+
+// A proxyLocator locates the repos of modules served by a Server.
+type proxyLocator struct{ srv *Server }
+
+var _ proxy.Locator = proxyLocator{}
+
+func (pl proxyLocator) LocateModule(modulePath string) (*proxy.Location,
+	error) {
+	s := pl.srv.siteFor(hostOf(modulePath))
+	if s == nil {
+		return nil, proxy.ErrNotFound
+	}
+	res, err := s.resolver.Resolve(modulePath)
+	if err != nil {
+		if errors.Is(err, resolve.ErrNotFound) {
+			return nil, proxy.ErrNotFound
+		}
+		return nil, errors.Wrap(err, "resolving module path")
+	}
+	if res.Metadata.URL == "" {
+		return nil, proxy.ErrNotFound
+	}
+	loc := &proxy.Location{RepoURL: res.Metadata.URL}
+
+	// Serve modules in subdirectories of the repo (i.e. 'mod/sub', or
+	// 'mod/sub/v2') from their directories, using their prefixed tags (i.e.
+	// 'sub/v1.2.3'). Only modules with known tags are served, so that package
+	// paths aren't mistaken for modules.
+	if res.Subpath != "" {
+		dir, major := res.Subpath, ""
+		if i := strings.LastIndexByte(dir, '/'); (i > -1) &&
+			resolve.IsMajorSuffix(dir[i+1:]) {
+			dir, major = dir[:i], dir[i+1:]
+		}
+		if (res.Major != "") || !hasModule(res.Metadata, dir, major) {
+			return nil, proxy.ErrNotFound
+		}
+		loc.Dir = dir
+		loc.TagPrefix = dir + "/"
+		return loc, nil
+	}
+
+	if s.layouts != nil {
+		layout, err := s.layouts.ModuleLayout(res.Metadata, res.Major)
+		if err != nil {
+			if errors.Is(err, repo.ErrUnknownMajor) {
+				return nil, proxy.ErrNotFound
+			}
+			return nil, errors.Wrap(err, "determining module layout")
+		}
+		loc.Dir = layout.Dir
+	}
+	return loc, nil
+}
+
+// hasModule returns true if r contains a tagged module in dir, with the major
+// version suffix major.
+func hasModule(r *repo.Repo, dir, major string) bool {
+	for _, mv := range r.Modules {
+		if (mv.Dir == dir) && (mv.Major == major) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// A responseRecorder is an http.ResponseWriter that records the status and
+// size of a response, and the module that it was for.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+	module string
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(p)
+	rr.bytes += n
+	return n, err
+}
+
+// setModule annotates the response being written to w with the module that it
+// is for, if w is a responseRecorder.
+func setModule(w http.ResponseWriter, module string) {
+	if rr, ok := w.(*responseRecorder); ok {
+		rr.module = module
+	}
+}
+
+// recordRequests wraps h, recording each request that it serves in
+// srv.metrics and the access log (if enabled).
+func (srv *Server) recordRequests(h http.Handler) http.Handler {
+	if (srv.metrics == nil) && (srv.accessLog == nil) {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start = time.Now()
+			rr    = &responseRecorder{ResponseWriter: w}
+		)
+		h.ServeHTTP(rr, r)
+		if rr.status == 0 {
+			rr.status = http.StatusOK
+		}
+
+		d := time.Since(start)
+		if srv.metrics != nil {
+			srv.metrics.ObserveRequest(rr.status, rr.module, d)
+		}
+		if srv.accessLog != nil {
+			srv.logAccess(r, rr, d)
+		}
+	})
+}
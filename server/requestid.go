@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// requestIDHeader is the header that request IDs are read from and written
+// to.
+const requestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// withRequestID wraps h, assigning an ID to each request so that it can be
+// correlated between responses and logs.
+//
+// Valid request IDs set by clients (or upstream proxies) are preserved.
+func withRequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(
+			context.WithValue(r.Context(), requestIDKey{}, id),
+		))
+	})
+}
+
+// requestID returns the ID of r, if it has one.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// isValidRequestID returns true if id is short, and only contains characters
+// that are safe to echo back in headers and logs.
+func isValidRequestID(id string) bool {
+	if (id == "") || (len(id) > 128) {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case (c >= 'a') && (c <= 'z'), (c >= 'A') && (c <= 'Z'),
+			(c >= '0') && (c <= '9'), (c == '-'), (c == '_'), (c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+package resolve
+
+// IsMajorSuffix returns true if elem is a major version suffix for a module
+// path, i.e. 'v2', 'v3', etc.
+func IsMajorSuffix(elem string) bool {
+	if (len(elem) < 2) || (elem[0] != 'v') {
+		return false
+	}
+	if elem[1] == '0' {
+		return false // disallow leading zeroes
+	}
+	for _, c := range elem[1:] {
+		if (c < '0') || (c > '9') {
+			return false
+		}
+	}
+	return elem != "v1"
+}
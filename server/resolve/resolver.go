@@ -0,0 +1,253 @@
+package resolve
+
+import (
+	stderrs "errors"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"go.stevenxie.me/vaingogh/pkg/urlutil"
+	"go.stevenxie.me/vaingogh/repo"
+	"go.stevenxie.me/vaingogh/template"
+)
+
+// NewResolver creates a new Resolver that resolves import paths under
+// baseURL, using validator to look up known repos.
+//
+// baseURL may contain a path component, i.e. 'example.com/go'.
+func NewResolver(baseURL string, validator repo.ValidatorService) *Resolver {
+	baseURL = urlutil.StripProtocol(baseURL)
+	baseURL = strings.Trim(baseURL, "/")
+	return &Resolver{
+		validator: validator,
+		baseURL:   baseURL,
+	}
+}
+
+type (
+	// A Resolver resolves import paths into the modules and repos that they
+	// belong to. It is safe for concurrent use.
+	Resolver struct {
+		validator repo.ValidatorService
+		baseURL   string
+	}
+
+	// A Result describes a resolved import path.
+	//
+	// For example, the import path 'example.com/go/mod/v2/sub/pkg' (with base
+	// URL 'example.com/go') resolves to:
+	//
+	//   Result{
+	//     ImportPath: "example.com/go/mod/v2/sub/pkg",
+	//     Root:       "example.com/go/mod",
+	//     Module:     "mod",
+	//     Major:      "v2",
+	//     Subpath:    "sub/pkg",
+	//     Repo:       "user/mod",
+	//   }
+	Result struct {
+		// ImportPath is the normalized import path that was resolved.
+		ImportPath string
+
+		// Root is the import path of the repo root, which is used as the prefix
+		// in go-import meta tags.
+		Root string
+
+		// Module is the name of the module, relative to the base URL.
+		Module string
+
+		// Major is the major version suffix of the module (i.e. 'v2'), if any.
+		Major string
+
+		// Subpath is the path of the requested package, relative to the module
+		// root.
+		Subpath string
+
+		// Repo is the canonical full name of the repo that contains the module.
+		Repo string
+
+		// Metadata contains metadata about the repo that contains the module.
+		Metadata *repo.Repo
+	}
+)
+
+// ErrNotFound is returned by a Resolver when an import path doesn't belong to
+// any known module.
+var ErrNotFound = stderrs.New("resolve: no matching module")
+
+// BaseURL returns the normalized base URL of the Resolver.
+func (r *Resolver) BaseURL() string { return r.baseURL }
+
+// Root returns the import path of the root of module.
+func (r *Resolver) Root(module string) string {
+	return r.baseURL + "/" + module
+}
+
+// IsBase returns true if importPath refers to the base URL itself.
+func (r *Resolver) IsBase(importPath string) bool {
+	importPath = urlutil.StripProtocol(importPath)
+	importPath = strings.Trim(importPath, "/")
+	rest, ok := trimBase(importPath, r.baseURL)
+	return ok && (rest == "")
+}
+
+// Resolve resolves an import path (i.e. 'example.com/go/mod/sub/pkg') into
+// the module that contains it.
+//
+// The longest prefix of the path that matches a known module is used. If no
+// module matches, ErrNotFound is returned.
+func (r *Resolver) Resolve(importPath string) (*Result, error) {
+	// Normalize import path.
+	importPath = urlutil.StripProtocol(importPath)
+	importPath = strings.Trim(importPath, "/")
+	if importPath == "" {
+		return nil, ErrNotFound
+	}
+	importPath = path.Clean(importPath)
+
+	// Ensure import path is under r.baseURL.
+	rest, ok := trimBase(importPath, r.baseURL)
+	if !ok || (rest == "") {
+		return nil, ErrNotFound
+	}
+	segments := strings.Split(rest, "/")
+
+	// Find the longest prefix of segments that matches a known module.
+	for i := len(segments); i > 0; i-- {
+		// Major version suffixes cannot be module names.
+		if (i > 1) && IsMajorSuffix(segments[i-1]) {
+			continue
+		}
+
+		module := strings.Join(segments[:i], "/")
+		meta, ok, err := r.validator.LookupRepo(
+			r.validator.DeriveRepoFullName(module),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "resolve: looking up repo")
+		}
+		if !ok {
+			continue
+		}
+
+		res := &Result{
+			ImportPath: r.baseURL + "/" + rest,
+			Root:       r.baseURL + "/" + module,
+			Module:     module,
+			Repo:       meta.Name,
+			Metadata:   meta,
+		}
+		sub := segments[i:]
+		if (len(sub) > 0) && IsMajorSuffix(sub[0]) {
+			res.Major = sub[0]
+			sub = sub[1:]
+		}
+		res.Subpath = strings.Join(sub, "/")
+		return res, nil
+	}
+	return nil, ErrNotFound
+}
+
+// ModulePath returns the full module path of the result, including its major
+// version suffix.
+func (res *Result) ModulePath() string {
+	if res.Major == "" {
+		return res.Root
+	}
+	return res.Root + "/" + res.Major
+}
+
+// Versions returns the tagged versions of the module that contains the
+// requested package, if known.
+func (res *Result) Versions() *repo.ModuleVersions {
+	if res.Metadata == nil {
+		return nil
+	}
+	return res.Metadata.FindModule(res.Subpath, res.Major)
+}
+
+// Import builds a template.Import for the result, using layouts (if non-nil)
+// to locate its module within its repo.
+//
+// If layouts doesn't know of the result's major version, ErrNotFound is
+// returned.
+func (res *Result) Import(layouts repo.LayoutService) (template.Import,
+	error) {
+	imp := template.Import{
+		Prefix:   res.Root,
+		Address:  res.ImportPath,
+		Repo:     res.Repo,
+		Major:    res.Major,
+		Metadata: res.Metadata,
+		Versions: res.Versions(),
+	}
+	if layouts != nil {
+		layout, err := layouts.ModuleLayout(res.Metadata, res.Major)
+		if err != nil {
+			if errors.Is(err, repo.ErrUnknownMajor) {
+				return imp, ErrNotFound
+			}
+			return imp, errors.Wrap(err, "resolve: determining module layout")
+		}
+		imp.Branch = layout.Branch
+		imp.Dir = layout.Dir
+	}
+	return imp, nil
+}
+
+// Imports builds template.Imports for the root modules of repos, sorted by
+// import path, i.e. for index pages.
+func (r *Resolver) Imports(repos []*repo.Repo) []template.Import {
+	imps := make([]template.Import, len(repos))
+	for i, meta := range repos {
+		root := r.Root(r.validator.DerivePartialName(meta.Name))
+		imps[i] = template.Import{
+			Prefix:   root,
+			Address:  root,
+			Repo:     meta.Name,
+			Metadata: meta,
+			Versions: meta.FindModule("", ""),
+		}
+	}
+	sort.Slice(imps, func(i, j int) bool {
+		return strings.ToLower(imps[i].Prefix) < strings.ToLower(imps[j].Prefix)
+	})
+	return imps
+}
+
+// trimBase removes base from the start of importPath, and returns the
+// remainder. The host portion is compared case-insensitively, and base must
+// end at a path segment boundary.
+func trimBase(importPath, base string) (rest string, ok bool) {
+	var (
+		host     = hostOf(importPath)
+		baseHost = hostOf(base)
+	)
+	if !strings.EqualFold(host, baseHost) {
+		return "", false
+	}
+
+	// Compare the paths that follow the hosts, which may differ in length
+	// from one another (since case folding can change the length of a host).
+	rest, basePath := importPath[len(host):], base[len(baseHost):]
+	if !strings.HasPrefix(rest, basePath) {
+		return "", false
+	}
+	rest = rest[len(basePath):]
+	if rest == "" {
+		return "", true
+	}
+	if rest[0] != '/' {
+		return "", false
+	}
+	return rest[1:], true
+}
+
+func hostOf(importPath string) string {
+	if i := strings.IndexByte(importPath, '/'); i > -1 {
+		return importPath[:i]
+	}
+	return importPath
+}
@@ -0,0 +1,52 @@
+//go:build go1.18
+// +build go1.18
+
+package resolve
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzResolve(f *testing.F) {
+	for _, seed := range []string{
+		"example.com/go/mod",
+		"example.com/go/mod/v2/sub/pkg",
+		"https://EXAMPLE.com/go/Mixed/",
+		"example.com/go/../go/mod/./pkg",
+		"example.com/go",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	r := NewResolver("example.com/go", newTestValidator("mod", "Mixed", "v2"))
+	f.Fuzz(func(t *testing.T, importPath string) {
+		res, err := r.Resolve(importPath)
+		if err != nil {
+			if err != ErrNotFound {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return
+		}
+
+		// The module (and its root) must prefix the resolved import path.
+		if !strings.HasPrefix(res.ImportPath, res.Root) {
+			t.Fatalf("root %q doesn't prefix import path %q", res.Root,
+				res.ImportPath)
+		}
+		if res.Root != r.Root(res.Module) {
+			t.Fatalf("root %q doesn't match module %q", res.Root, res.Module)
+		}
+
+		// The import path must be made up of the root, major, and subpath.
+		want := res.ModulePath()
+		if res.Subpath != "" {
+			want += "/" + res.Subpath
+		}
+		if res.ImportPath != want {
+			t.Fatalf("import path %q doesn't match its parts %q",
+				res.ImportPath, want)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package resolve
+
+import (
+	"strings"
+	"testing"
+
+	"go.stevenxie.me/vaingogh/repo"
+)
+
+// testValidator is a repo.ValidatorService for the repos of the user 'user'.
+type testValidator struct{ snap *repo.Snapshot }
+
+var _ repo.ValidatorService = testValidator{}
+
+func newTestValidator(names ...string) testValidator {
+	repos := make([]*repo.Repo, len(names))
+	for i, name := range names {
+		repos[i] = &repo.Repo{Name: "user/" + name}
+	}
+	return testValidator{snap: repo.NewSnapshot(repos, nil)}
+}
+
+func (tv testValidator) IsRepoValid(name string) (bool, error) {
+	return tv.snap.Contains(name), nil
+}
+
+func (tv testValidator) LookupRepo(name string) (*repo.Repo, bool, error) {
+	r, ok := tv.snap.Lookup(name)
+	return r, ok, nil
+}
+
+func (testValidator) DeriveRepoFullName(partial string) string {
+	return "user/" + partial
+}
+
+func (testValidator) DerivePartialName(name string) string {
+	return strings.TrimPrefix(name, "user/")
+}
+
+func TestResolve(t *testing.T) {
+	validator := newTestValidator("mod", "Mixed", "v2", "mod.v2")
+	cases := []struct {
+		name       string
+		baseURL    string
+		importPath string
+		want       *Result // nil if ErrNotFound is expected
+	}{
+		{
+			name:       "module root",
+			baseURL:    "example.com",
+			importPath: "example.com/mod",
+			want: &Result{
+				ImportPath: "example.com/mod",
+				Root:       "example.com/mod",
+				Module:     "mod",
+				Repo:       "user/mod",
+			},
+		},
+		{
+			name:       "base path",
+			baseURL:    "https://example.com/go/",
+			importPath: "example.com/go/mod",
+			want: &Result{
+				ImportPath: "example.com/go/mod",
+				Root:       "example.com/go/mod",
+				Module:     "mod",
+				Repo:       "user/mod",
+			},
+		},
+		{
+			name:       "deep subpackage",
+			baseURL:    "example.com/go",
+			importPath: "example.com/go/mod/a/b/c/d",
+			want: &Result{
+				ImportPath: "example.com/go/mod/a/b/c/d",
+				Root:       "example.com/go/mod",
+				Module:     "mod",
+				Subpath:    "a/b/c/d",
+				Repo:       "user/mod",
+			},
+		},
+		{
+			name:       "trailing slashes",
+			baseURL:    "example.com",
+			importPath: "https://example.com/mod/pkg//",
+			want: &Result{
+				ImportPath: "example.com/mod/pkg",
+				Root:       "example.com/mod",
+				Module:     "mod",
+				Subpath:    "pkg",
+				Repo:       "user/mod",
+			},
+		},
+		{
+			name:       "case folding",
+			baseURL:    "example.com/go",
+			importPath: "EXAMPLE.com/go/mixed/pkg",
+			want: &Result{
+				ImportPath: "example.com/go/mixed/pkg",
+				Root:       "example.com/go/mixed",
+				Module:     "mixed",
+				Subpath:    "pkg",
+				Repo:       "user/Mixed",
+			},
+		},
+		{
+			name:       "major suffix",
+			baseURL:    "example.com",
+			importPath: "example.com/mod/v2",
+			want: &Result{
+				ImportPath: "example.com/mod/v2",
+				Root:       "example.com/mod",
+				Module:     "mod",
+				Major:      "v2",
+				Repo:       "user/mod",
+			},
+		},
+		{
+			name:       "major suffix with subpackage",
+			baseURL:    "example.com",
+			importPath: "example.com/mod/v12/sub/pkg",
+			want: &Result{
+				ImportPath: "example.com/mod/v12/sub/pkg",
+				Root:       "example.com/mod",
+				Module:     "mod",
+				Major:      "v12",
+				Subpath:    "sub/pkg",
+				Repo:       "user/mod",
+			},
+		},
+		{
+			name:       "non-major suffixes are subpackages",
+			baseURL:    "example.com",
+			importPath: "example.com/mod/v1/v02",
+			want: &Result{
+				ImportPath: "example.com/mod/v1/v02",
+				Root:       "example.com/mod",
+				Module:     "mod",
+				Subpath:    "v1/v02",
+				Repo:       "user/mod",
+			},
+		},
+		{
+			name:       "module named like a major suffix",
+			baseURL:    "example.com",
+			importPath: "example.com/v2/pkg",
+			want: &Result{
+				ImportPath: "example.com/v2/pkg",
+				Root:       "example.com/v2",
+				Module:     "v2",
+				Subpath:    "pkg",
+				Repo:       "user/v2",
+			},
+		},
+		{
+			name:       "unknown module",
+			baseURL:    "example.com",
+			importPath: "example.com/other/pkg",
+		},
+		{
+			name:       "base URL itself",
+			baseURL:    "example.com/go",
+			importPath: "example.com/go/",
+		},
+		{
+			name:       "other host",
+			baseURL:    "example.com",
+			importPath: "example.org/mod",
+		},
+		{
+			name:       "base path without segment boundary",
+			baseURL:    "example.com/go",
+			importPath: "example.com/gomod",
+		},
+		{
+			name:       "empty path",
+			baseURL:    "example.com",
+			importPath: "/",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := NewResolver(c.baseURL, validator).Resolve(c.importPath)
+			if c.want == nil {
+				if err != ErrNotFound {
+					t.Fatalf("expected ErrNotFound, got (%+v, %v)", res, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Metadata == nil {
+				t.Error("expected repo metadata")
+			}
+			res.Metadata = nil
+			if *res != *c.want {
+				t.Errorf("expected %+v, got %+v", c.want, res)
+			}
+		})
+	}
+}
+
+func TestIsBase(t *testing.T) {
+	r := NewResolver("example.com/go", newTestValidator())
+	for path, want := range map[string]bool{
+		"example.com/go":          true,
+		"https://EXAMPLE.com/go/": true,
+		"example.com/go/mod":      false,
+		"example.com/gopher":      false,
+		"example.com":             false,
+	} {
+		if got := r.IsBase(path); got != want {
+			t.Errorf("IsBase(%q): expected %t, got %t", path, want, got)
+		}
+	}
+}
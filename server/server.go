@@ -2,13 +2,19 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/sirupsen/logrus"
 	"go.stevenxie.me/api/pkg/zero"
+	"golang.org/x/crypto/acme/autocert"
 
-	"go.stevenxie.me/vaingogh/pkg/urlutil"
+	"go.stevenxie.me/vaingogh/metrics"
+	"go.stevenxie.me/vaingogh/proxy"
 	"go.stevenxie.me/vaingogh/repo"
 	"go.stevenxie.me/vaingogh/template"
 )
@@ -21,24 +27,94 @@ func New(
 	opts ...func(*Config),
 ) (*Server, error) {
 	cfg := Config{
-		HTTPServer: new(http.Server),
-		Logger:     zero.Logger(),
+		HTTPServer:    new(http.Server),
+		Logger:        zero.Logger(),
+		LivenessPath:  DefaultLivenessPath,
+		ReadinessPath: DefaultReadinessPath,
+		MetricsPath:   DefaultMetricsPath,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
-	// Normalize baseURL.
-	baseURL = urlutil.StripProtocol(baseURL)
-	baseURL = strings.Trim(baseURL, "/")
+	// Build a site for each host, the first of which is the primary host.
+	hosts := append([]Host{{
+		BaseURL:   baseURL,
+		Generator: generator,
+		Validator: validator,
+		Layouts:   cfg.Layouts,
+		Snapshots: cfg.Snapshots,
+		Events:    cfg.Events,
+		Sources:   cfg.Sources,
+		Refresher: cfg.Refresher,
+	}}, cfg.Hosts...)
+	seen := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		host := strings.ToLower(hostOf(h.BaseURL))
+		if seen[host] {
+			return nil, errors.Newf("server: host '%s' is configured more than "+
+				"once", host)
+		}
+		seen[host] = true
+	}
+	var (
+		sites   = make([]*site, len(hosts))
+		sources []repo.StatusService
+	)
+	for i := range hosts {
+		sites[i] = newSite(&hosts[i])
+		sources = append(sources, hosts[i].Sources...)
+	}
+
+	srv := &Server{
+		sites:   sites,
+		sources: sources,
+		httpsrv: cfg.HTTPServer,
+		log:     cfg.Logger,
+
+		adminToken: cfg.AdminToken,
+
+		livenessPath:  cfg.LivenessPath,
+		readinessPath: cfg.ReadinessPath,
+		maxStaleness:  cfg.MaxStaleness,
 
-	return &Server{
-		generator: generator,
-		validator: validator,
-		httpsrv:   cfg.HTTPServer,
-		log:       cfg.Logger,
-		baseURL:   baseURL,
-	}, nil
+		metrics:     cfg.Metrics,
+		metricsPath: cfg.MetricsPath,
+		accessLog:   cfg.AccessLog,
+
+		cacheMaxAge: cfg.CacheMaxAge,
+	}
+	if (cfg.Metrics != nil) && (cfg.AdminAddr != "") {
+		srv.adminsrv = &http.Server{Addr: cfg.AdminAddr}
+	}
+	if cfg.TLS != nil {
+		var (
+			manager *autocert.Manager
+			err     error
+		)
+		if srv.tls, srv.certs, manager, err = srv.buildTLS(
+			cfg.TLS,
+		); err != nil {
+			return nil, err
+		}
+		if addr := cfg.TLS.RedirectAddr; addr != "" {
+			srv.redirectsrv = &http.Server{Addr: addr}
+			srv.acme = manager
+		}
+	}
+	if cfg.ProxyPath != "" {
+		srv.proxyPath = "/" + strings.Trim(cfg.ProxyPath, "/")
+		opts := []func(*proxy.Config){
+			func(pc *proxy.Config) {
+				pc.Logger = cfg.Logger.WithField("component", "proxy.Proxy")
+			},
+		}
+		srv.proxy = proxy.New(
+			proxyLocator{srv},
+			append(opts, cfg.ProxyOptions...)...,
+		)
+	}
+	return srv, nil
 }
 
 type (
@@ -47,35 +123,230 @@ type (
 		httpsrv *http.Server
 		log     logrus.FieldLogger
 
-		generator template.Generator
-		validator repo.ValidatorService
-		baseURL   string
+		sites   []*site              // the first site is the primary site
+		sources []repo.StatusService // the sources of every site
+
+		adminToken string
+
+		livenessPath  string
+		readinessPath string
+		maxStaleness  time.Duration
+
+		metrics     *metrics.Metrics
+		metricsPath string
+		adminsrv    *http.Server
+		accessLog   *AccessLogConfig
+
+		cacheMaxAge time.Duration
+
+		tls         *tls.Config
+		certs       *certReloader     // nil unless using certificate files
+		acme        *autocert.Manager // nil unless redirecting with ACME
+		redirectsrv *http.Server
+
+		proxy     *proxy.Proxy
+		proxyPath string
 	}
 
 	// Config configures a Server.
 	Config struct {
 		HTTPServer *http.Server
 		Logger     logrus.FieldLogger
+
+		// Layouts is used to locate modules within their repos. If nil, all
+		// modules are assumed to be located at the root of their repo's
+		// default branch.
+		Layouts repo.LayoutService
+
+		// Snapshots is used to list modules on the index page. If nil, the index
+		// page will be empty.
+		Snapshots repo.SnapshotService
+
+		// Sources are the sources of Go repos that are reported on by the
+		// status API. The status API of each host only reports on its own
+		// sources, but the readiness endpoint reports on those of every host.
+		Sources []repo.StatusService
+
+		// If both Refresher and AdminToken are set, the server will expose an
+		// admin endpoint at '/-/refresh' that uses Refresher to refresh its
+		// repos (or, upon requests to an additional host, that host's
+		// Refresher). Requests must be authenticated with AdminToken as a
+		// bearer token.
+		Refresher  repo.RefresherService
+		AdminToken string
+
+		// If ProxyPath is set, the server will serve its modules using the
+		// GOPROXY protocol under ProxyPath (i.e. '/proxy'), so that clients can
+		// set 'GOPROXY=https://example.com/proxy'. ProxyOptions configure the
+		// proxy.
+		ProxyPath    string
+		ProxyOptions []func(*proxy.Config)
+
+		// LivenessPath and ReadinessPath are the paths of the liveness and
+		// readiness endpoints, which default to DefaultLivenessPath and
+		// DefaultReadinessPath.
+		//
+		// The server is ready once each of its Sources has been listed
+		// successfully, for as long as their last successful listings are no
+		// older than MaxStaleness. If MaxStaleness is zero, listings never
+		// become stale.
+		LivenessPath  string
+		ReadinessPath string
+		MaxStaleness  time.Duration
+
+		// If Metrics is set, the server will record metrics about the requests
+		// that it serves, and expose them at MetricsPath (which defaults to
+		// DefaultMetricsPath).
+		//
+		// If AdminAddr is set, metrics will be served by a separate admin
+		// listener on AdminAddr, rather than alongside vanity import pages.
+		Metrics     *metrics.Metrics
+		MetricsPath string
+		AdminAddr   string
+
+		// If AccessLog is set, the server will log each request that it
+		// serves.
+		AccessLog *AccessLogConfig
+
+		// If Events is set, generated pages will be cached until Events reports
+		// that the list of repos has changed.
+		Events repo.SubscriberService
+
+		// CacheMaxAge is how long clients (and CDNs) may cache pages for
+		// without revalidating them. If zero, clients must always revalidate.
+		CacheMaxAge time.Duration
+
+		// Hosts are additional vanity domains to serve, besides the one at the
+		// base URL given to New. Requests are routed to hosts by their Host
+		// header; requests for unknown hosts receive a 404 response.
+		Hosts []Host
+
+		// If TLS is set, the server will serve HTTPS rather than plain HTTP.
+		TLS *TLSConfig
 	}
 )
 
-// ListenAndServe listens and serves responses to network requests on the
-// specified address.
+// ListenAndServe listens on the TCP address addr, and then calls Serve to
+// serve responses to network requests.
 func (srv *Server) ListenAndServe(addr string) error {
-	var (
-		httpsrv = srv.httpsrv
-		hlog    = srv.log.WithField("component", "handler")
-	)
+	ln, err := Listen(ListenConfig{Addr: addr})
+	if err != nil {
+		return err
+	}
+	return srv.Serve(ln)
+}
 
+// Serve serves responses to network requests received by ln, using HTTPS if
+// the server is configured with TLS.
+//
+// If the server has an admin listener or an HTTPS redirect listener, they are
+// started as well, and the first error encountered by any listener is
+// returned.
+func (srv *Server) Serve(ln net.Listener) error {
 	// Configure HTTP server.
-	httpsrv.Handler = srv.handler(hlog)
+	addr := ln.Addr().String()
+	httpsrv := srv.httpsrv
+	httpsrv.Handler = srv.buildHandler()
 	httpsrv.Addr = addr
 
-	srv.log.WithField("addr", addr).Info("Listening for connections...")
-	return httpsrv.ListenAndServe()
+	var (
+		errs  = make(chan error, 3)
+		serve = func(msg, addr string, fn func() error) {
+			go func() {
+				srv.log.WithField("addr", addr).Info(msg)
+				errs <- fn()
+			}()
+		}
+	)
+
+	// Start admin and redirect listeners alongside the main listener.
+	if adminsrv := srv.adminsrv; adminsrv != nil {
+		adminsrv.Handler = srv.buildAdminHandler()
+		serve("Listening for admin connections...", adminsrv.Addr,
+			adminsrv.ListenAndServe)
+	}
+	if redirectsrv := srv.redirectsrv; redirectsrv != nil {
+		// Redirect to the main listener's port, if it has one (and otherwise,
+		// to the default HTTPS port).
+		_, port, _ := net.SplitHostPort(addr)
+		redirectsrv.Handler = redirectHandler(port)
+		if srv.acme != nil {
+			redirectsrv.Handler = srv.acme.HTTPHandler(redirectsrv.Handler)
+		}
+		serve("Redirecting connections to HTTPS...", redirectsrv.Addr,
+			redirectsrv.ListenAndServe)
+	}
+	if srv.tls != nil {
+		httpsrv.TLSConfig = srv.tls
+		serve("Listening for TLS connections...", addr, func() error {
+			return httpsrv.ServeTLS(ln, "", "")
+		})
+	} else {
+		serve("Listening for connections...", addr, func() error {
+			return httpsrv.Serve(ln)
+		})
+	}
+	return <-errs
+}
+
+// buildHandler builds the root http.Handler for srv, which routes requests
+// to admin endpoints and vanity import pages.
+func (srv *Server) buildHandler() http.Handler {
+	mux := http.NewServeMux()
+	if srv.adminToken != "" {
+		mux.Handle("/-/refresh", srv.refreshHandler(
+			srv.log.WithField("component", "refreshHandler"),
+		))
+	}
+	mux.Handle(srv.livenessPath, livenessHandler(
+		srv.log.WithField("component", "livenessHandler"),
+	))
+	mux.Handle(srv.readinessPath, srv.readinessHandler(
+		srv.log.WithField("component", "readinessHandler"),
+	))
+	if (srv.metrics != nil) && (srv.adminsrv == nil) {
+		mux.Handle(srv.metricsPath, srv.metrics.Handler())
+	}
+	mux.Handle("/-/info", srv.infoHandler(
+		srv.log.WithField("component", "infoHandler"),
+	))
+	if srv.proxy != nil {
+		mux.Handle(srv.proxyPath+"/", http.StripPrefix(srv.proxyPath, srv.proxy))
+	}
+	mux.Handle(apiPrefix, srv.apiHandler(
+		srv.log.WithField("component", "apiHandler"),
+	))
+	mux.Handle("/", srv.handler(srv.log.WithField("component", "handler")))
+	return withRequestID(srv.recordRequests(mux))
 }
 
-// Shutdown gracefully shuts down the server.
+// buildAdminHandler builds the http.Handler for srv's admin listener.
+func (srv *Server) buildAdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(srv.metricsPath, srv.metrics.Handler())
+	return mux
+}
+
+// Shutdown gracefully shuts down the server, and its admin listener (if
+// any).
 func (srv *Server) Shutdown(ctx context.Context) error {
+	for _, s := range srv.sites {
+		if s.unsubscribe != nil {
+			s.unsubscribe()
+		}
+	}
+	if srv.certs != nil {
+		srv.certs.Stop()
+	}
+	if srv.adminsrv != nil {
+		if err := srv.adminsrv.Shutdown(ctx); err != nil {
+			return errors.Wrap(err, "server: shutting down admin listener")
+		}
+	}
+	if srv.redirectsrv != nil {
+		if err := srv.redirectsrv.Shutdown(ctx); err != nil {
+			return errors.Wrap(err, "server: shutting down redirect listener")
+		}
+	}
 	return srv.httpsrv.Shutdown(ctx)
 }
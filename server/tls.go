@@ -0,0 +1,267 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"go.stevenxie.me/api/pkg/zero"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// DefaultCertReloadInterval is the default interval at which certificate
+// files are checked for changes.
+const DefaultCertReloadInterval = time.Minute
+
+type (
+	// A TLSConfig configures a Server to serve HTTPS, using either certificate
+	// files or certificates obtained automatically using ACME.
+	TLSConfig struct {
+		// CertFile and KeyFile are the paths to a PEM-encoded certificate (or
+		// certificate chain) and its private key. They are checked for changes
+		// every ReloadInterval (which defaults to DefaultCertReloadInterval),
+		// and reloaded if they have changed.
+		CertFile       string
+		KeyFile        string
+		ReloadInterval time.Duration
+
+		// If ACME is set, certificates are obtained automatically for the
+		// server's hosts instead of being loaded from files.
+		ACME *ACMEConfig
+
+		// If RedirectAddr is set, the server will listen for plain HTTP
+		// requests on RedirectAddr (i.e. ':80'), and redirect them to HTTPS.
+		// When using ACME, the listener also answers 'http-01' challenges.
+		RedirectAddr string
+	}
+
+	// An ACMEConfig configures how certificates are obtained using ACME.
+	ACMEConfig struct {
+		// DirectoryURL is the URL of the ACME directory of the certificate
+		// authority. It defaults to that of Let's Encrypt.
+		DirectoryURL string
+
+		// Email is the contact email address of the ACME account.
+		Email string
+
+		// CacheDir is the directory that certificates and account keys are
+		// cached in. If empty, certificates are only cached in memory, and are
+		// obtained again whenever the server restarts.
+		CacheDir string
+
+		// RootCAs, if set, are the certificate authorities that are trusted
+		// when connecting to the ACME directory (i.e. a local test CA).
+		RootCAs *x509.CertPool
+	}
+)
+
+// buildTLS builds the tls.Config described by cfg, for serving the hosts of
+// srv's sites.
+//
+// If cfg uses certificate files, the returned certReloader must be stopped
+// once it is no longer needed. If cfg uses ACME, the returned
+// autocert.Manager should handle 'http-01' challenges.
+func (srv *Server) buildTLS(cfg *TLSConfig) (*tls.Config, *certReloader,
+	*autocert.Manager, error) {
+	usesFiles := (cfg.CertFile != "") || (cfg.KeyFile != "")
+	if usesFiles == (cfg.ACME != nil) {
+		return nil, nil, nil, errors.New("server: exactly one of certificate " +
+			"files and ACME must be configured")
+	}
+
+	// Load certificate files.
+	if usesFiles {
+		interval := cfg.ReloadInterval
+		if interval == 0 {
+			interval = DefaultCertReloadInterval
+		}
+		certs, err := newCertReloader(
+			cfg.CertFile, cfg.KeyFile,
+			interval,
+			srv.log.WithField("component", "certReloader"),
+		)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return &tls.Config{
+			GetCertificate: certs.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}, certs, nil, nil
+	}
+
+	// Obtain certificates using ACME.
+	acfg := cfg.ACME
+	client := &acme.Client{DirectoryURL: acfg.DirectoryURL}
+	if client.DirectoryURL == "" {
+		client.DirectoryURL = acme.LetsEncryptURL
+	}
+	if acfg.RootCAs != nil {
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: acfg.RootCAs},
+			},
+		}
+	}
+	hosts := make([]string, len(srv.sites))
+	for i, s := range srv.sites {
+		hosts[i] = hostOf(s.resolver.BaseURL())
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Email:      acfg.Email,
+		Client:     client,
+	}
+	if acfg.CacheDir != "" {
+		manager.Cache = autocert.DirCache(acfg.CacheDir)
+	}
+	tlscfg := manager.TLSConfig()
+	tlscfg.MinVersion = tls.VersionTLS12
+	return tlscfg, nil, manager, nil
+}
+
+// redirectHandler redirects requests to HTTPS, on the port httpsPort.
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if (httpsPort != "") && (httpsPort != "443") {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		target := url.URL{
+			Scheme:   "https",
+			Host:     host,
+			Path:     r.URL.Path,
+			RawQuery: r.URL.RawQuery,
+		}
+		http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+	})
+}
+
+// A certReloader provides a certificate loaded from files, which it reloads
+// whenever they change.
+//
+// If the changed files fail to load, the previous certificate is kept.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	log      logrus.FieldLogger
+
+	cert  atomic.Value // *tls.Certificate
+	state [2]fileState // the states of certFile and keyFile when last loaded
+
+	stop chan zero.Struct
+	once sync.Once
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (fs fileState) equal(other fileState) bool {
+	return fs.modTime.Equal(other.modTime) && (fs.size == other.size)
+}
+
+func newCertReloader(
+	certFile, keyFile string,
+	interval time.Duration,
+	log logrus.FieldLogger,
+) (*certReloader, error) {
+	cr := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		log:      log.WithField("certFile", certFile),
+		stop:     make(chan zero.Struct),
+	}
+	state, err := cr.stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "server: reading certificate file info")
+	}
+	if err = cr.load(); err != nil {
+		return nil, err
+	}
+	cr.state = state
+	go cr.run(interval)
+	return cr, nil
+}
+
+// GetCertificate returns the current certificate, for use as
+// tls.Config.GetCertificate.
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (
+	*tls.Certificate, error) {
+	return cr.cert.Load().(*tls.Certificate), nil
+}
+
+// Stop stops checking the certificate files for changes.
+func (cr *certReloader) Stop() {
+	cr.once.Do(func() { close(cr.stop) })
+}
+
+func (cr *certReloader) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cr.stop:
+			return
+		case <-ticker.C:
+			cr.check()
+		}
+	}
+}
+
+// check reloads the certificate if its files have changed since they were
+// last checked.
+func (cr *certReloader) check() {
+	state, err := cr.stat()
+	if err != nil {
+		cr.log.WithError(err).Error("Failed to read certificate file info.")
+		return
+	}
+	if state[0].equal(cr.state[0]) && state[1].equal(cr.state[1]) {
+		return
+	}
+
+	// Record the files' new state regardless of whether or not they load, so
+	// that a broken certificate is only reported once.
+	cr.state = state
+	if err = cr.load(); err != nil {
+		cr.log.WithError(err).
+			Error("Failed to reload certificate; keeping previous certificate.")
+		return
+	}
+	cr.log.Info("Reloaded certificate.")
+}
+
+func (cr *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "server: loading certificate")
+	}
+	cr.cert.Store(&cert)
+	return nil
+}
+
+func (cr *certReloader) stat() (state [2]fileState, err error) {
+	for i, name := range []string{cr.certFile, cr.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return state, err
+		}
+		state[i] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+	return state, nil
+}
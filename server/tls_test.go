@@ -0,0 +1,315 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.stevenxie.me/api/pkg/zero"
+)
+
+// writeTestCert writes a self-signed certificate for commonName, and its key,
+// to certFile and keyFile, with modification times of modTime.
+func writeTestCert(
+	t *testing.T,
+	certFile, keyFile, commonName string,
+	modTime time.Time,
+) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey,
+		key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err = ioutil.WriteFile(name, pem.EncodeToMemory(block),
+			0600); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// certName returns the common name of the certificate served by cr.
+func certName(t *testing.T, cr *certReloader) string {
+	cert, err := cr.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vaingogh-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		certFile = filepath.Join(dir, "cert.pem")
+		keyFile  = filepath.Join(dir, "key.pem")
+		modTime  = time.Now().Add(-time.Hour).Truncate(time.Second)
+	)
+
+	writeTestCert(t, certFile, keyFile, "old.example.com", modTime)
+	cr, err := newCertReloader(certFile, keyFile, time.Hour, zero.Logger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cr.Stop()
+	if name := certName(t, cr); name != "old.example.com" {
+		t.Fatalf("expected initial certificate, got '%s'", name)
+	}
+
+	// Unchanged files aren't reloaded.
+	cr.check()
+	if name := certName(t, cr); name != "old.example.com" {
+		t.Errorf("expected unchanged certificate, got '%s'", name)
+	}
+
+	// Changed files are reloaded.
+	modTime = modTime.Add(time.Minute)
+	writeTestCert(t, certFile, keyFile, "new.example.com", modTime)
+	cr.check()
+	if name := certName(t, cr); name != "new.example.com" {
+		t.Errorf("expected reloaded certificate, got '%s'", name)
+	}
+
+	// Broken files are not loaded, and the previous certificate is kept.
+	modTime = modTime.Add(time.Minute)
+	if err = ioutil.WriteFile(certFile, []byte("not a certificate"),
+		0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(certFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	cr.check()
+	if name := certName(t, cr); name != "new.example.com" {
+		t.Errorf("expected previous certificate to be kept, got '%s'", name)
+	}
+
+	// Missing files are reported when creating a certReloader.
+	if _, err = newCertReloader(
+		filepath.Join(dir, "missing.pem"), keyFile,
+		time.Hour,
+		zero.Logger(),
+	); err == nil {
+		t.Error("expected error for missing certificate file")
+	}
+}
+
+func TestBuildTLS(t *testing.T) {
+	srv := &Server{
+		log:   zero.Logger(),
+		sites: []*site{newSite(&Host{BaseURL: "go.example.com"})},
+	}
+	for _, cfg := range []*TLSConfig{
+		{},
+		{CertFile: "cert.pem", ACME: new(ACMEConfig)},
+	} {
+		if _, _, _, err := srv.buildTLS(cfg); err == nil {
+			t.Errorf("expected error for config %+v", cfg)
+		}
+	}
+
+	tlscfg, certs, manager, err := srv.buildTLS(&TLSConfig{
+		ACME: &ACMEConfig{DirectoryURL: "https://acme.invalid/directory"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (certs != nil) || (manager == nil) || (tlscfg.GetCertificate == nil) {
+		t.Fatal("expected an ACME-backed TLS config")
+	}
+	if err = manager.HostPolicy(context.Background(), "go.example.com"); err != nil {
+		t.Errorf("expected site host to be allowed: %v", err)
+	}
+	if err = manager.HostPolicy(context.Background(), "other.example.com"); err == nil {
+		t.Error("expected unknown host to be rejected")
+	}
+}
+
+// newTestACMEServer starts an ACME (RFC 8555) certificate authority over
+// HTTPS, which considers every order to be authorized already, and issues
+// certificates for the names in their CSRs.
+//
+// JWS signatures and nonces aren't verified.
+func newTestACMEServer(t *testing.T) *httptest.Server {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	var (
+		ts   *httptest.Server
+		leaf []byte // the DER encoding of the last issued certificate
+	)
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	ts = httptest.NewUnstartedServer(http.HandlerFunc(func(
+		w http.ResponseWriter,
+		r *http.Request,
+	) {
+		w.Header().Set("Replay-Nonce", "nonce")
+		order := map[string]interface{}{
+			"status":   "ready",
+			"finalize": ts.URL + "/finalize",
+		}
+		switch r.URL.Path {
+		case "/directory":
+			writeJSON(w, http.StatusOK, map[string]string{
+				"newNonce":   ts.URL + "/new-nonce",
+				"newAccount": ts.URL + "/new-account",
+				"newOrder":   ts.URL + "/new-order",
+			})
+		case "/new-nonce":
+		case "/new-account":
+			w.Header().Set("Location", ts.URL+"/account")
+			writeJSON(w, http.StatusCreated, map[string]string{"status": "valid"})
+		case "/new-order":
+			w.Header().Set("Location", ts.URL+"/order")
+			writeJSON(w, http.StatusCreated, order)
+		case "/finalize":
+			var (
+				jws struct{ Payload string }
+				req struct{ CSR string }
+			)
+			if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+			json.Unmarshal(payload, &req)
+			der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+			csr, err := x509.ParseCertificateRequest(der)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			names := csr.DNSNames
+			if len(names) == 0 {
+				names = []string{csr.Subject.CommonName}
+			}
+			if leaf, err = x509.CreateCertificate(rand.Reader, &x509.Certificate{
+				SerialNumber: big.NewInt(2),
+				Subject:      pkix.Name{CommonName: csr.Subject.CommonName},
+				DNSNames:     names,
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(24 * time.Hour),
+				KeyUsage:     x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}, caTpl, csr.PublicKey, caKey); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			order["status"] = "valid"
+			order["certificate"] = ts.URL + "/cert"
+			w.Header().Set("Location", ts.URL+"/order")
+			writeJSON(w, http.StatusOK, order)
+		case "/cert":
+			w.Header().Set("Content-Type", "application/pem-certificate-chain")
+			pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: leaf})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0) // expected TLS errors
+	ts.StartTLS()
+	return ts
+}
+
+func TestBuildTLSObtainsACMECertificates(t *testing.T) {
+	ts := newTestACMEServer(t)
+	defer ts.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+
+	srv := &Server{
+		log:   zero.Logger(),
+		sites: []*site{newSite(&Host{BaseURL: "go.example.com"})},
+	}
+	getCertificate := func(rootCAs *x509.CertPool) (*tls.Certificate, error) {
+		tlscfg, _, _, err := srv.buildTLS(&TLSConfig{
+			ACME: &ACMEConfig{
+				DirectoryURL: ts.URL + "/directory",
+				RootCAs:      rootCAs,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tlscfg.GetCertificate(&tls.ClientHelloInfo{
+			ServerName: "go.example.com",
+		})
+	}
+
+	// The directory's certificate is only trusted by way of RootCAs.
+	if _, err := getCertificate(nil); err == nil {
+		t.Error("expected error without the directory's root CA")
+	}
+
+	cert, err := getCertificate(roots)
+	if err != nil {
+		t.Fatalf("obtaining certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = leaf.VerifyHostname("go.example.com"); err != nil {
+		t.Errorf("expected certificate for site host: %v", err)
+	}
+}
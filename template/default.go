@@ -5,11 +5,22 @@ const defaultTemplate = `<!DOCTYPE html>
   <head>
     <meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
     <meta name="go-import" content="{{ .Prefix }} {{ .VCSType }} {{ .ImportURL }}">
-    <meta name="go-source" content="{{ .Prefix }} {{ .SourceURL }} {{ .SourceTreeURL }} {{ .SourceBlobURL }}">
-    <meta http-equiv="refresh" content="0; url=https://godoc.org/{{ .Address }}">
+    {{- with .ProxyURL }}
+    <meta name="go-import" content="{{ $.Prefix }} mod {{ . }}">
+    {{- end }}
+    {{- if .SourceURL }}
+    <meta name="go-source" content="{{ .ModulePath }} {{ .SourceURL }} {{ .SourceTreeURL }} {{ .SourceBlobURL }}">
+    {{- end }}
+    {{- if and .MetaRefresh .DocsURL }}
+    <meta http-equiv="refresh" content="0; url={{ .DocsURL }}">
+    {{- end }}
   </head>
   <body>
-    Nothing to see here; <a href="https://godoc.org/{{ .Address }}">move along</a>.
+    {{- if .DocsURL }}
+    Nothing to see here; <a href="{{ .DocsURL }}">move along</a>.
+    {{- else }}
+    Nothing to see here.
+    {{- end }}
   </body>
 </html>
 `
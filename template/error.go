@@ -0,0 +1,31 @@
+package template
+
+// DefaultErrorTemplate is the default template for error pages.
+const DefaultErrorTemplate = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1"/>
+    <title>{{ .Status }} {{ .StatusText }}{{ with .Site.Name }} - {{ . }}{{ end }}</title>
+    <style>
+      body {
+        max-width: 42rem;
+        margin: 3rem auto;
+        padding: 0 1rem;
+        font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica,
+          Arial, sans-serif;
+        line-height: 1.5;
+        color: #24292e;
+      }
+      h1 { font-size: 1.5rem; }
+      .status { color: #586069; }
+      .request-id { font-size: 0.875rem; color: #586069; }
+    </style>
+  </head>
+  <body>
+    <h1><span class="status">{{ .Status }}</span> {{ .StatusText }}</h1>
+    {{ with .Message }}<p>{{ . }}</p>{{ end }}
+    {{ with .RequestID }}<p class="request-id">Request ID: <code>{{ . }}</code></p>{{ end }}
+  </body>
+</html>
+`
@@ -0,0 +1,144 @@
+package template
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FuncMap contains the functions that are available to page templates, in
+// addition to the builtin template functions:
+//
+//	docsURL BASE PATH      the URL of the documentation page of the import
+//	                       path PATH on the documentation site at BASE, or ""
+//	                       if BASE is empty or "none" (see DocsURL)
+//	badge LABEL MSG COLOR  the URL of a shields.io badge image
+//	markdown TEXT          TEXT rendered as HTML, supporting inline Markdown
+//	                       (code spans, emphasis, and links)
+//	lower TEXT             TEXT in lower case
+//	upper TEXT             TEXT in upper case
+//	join SEP LIST          the elements of LIST joined by SEP
+//	formatTime LAYOUT T    T formatted according to LAYOUT (see time.Format)
+//	timeAgo T              the time elapsed since T in rough, human-readable
+//	                       terms (i.e. "3 days ago")
+//
+// Functions whose last argument is the value being operated on can be used in
+// pipelines, i.e. '{{ .Topics | join ", " }}'.
+var FuncMap = template.FuncMap{
+	"docsURL":    DocsURL,
+	"badge":      badgeURL,
+	"markdown":   renderMarkdown,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"join":       join,
+	"formatTime": formatTime,
+	"timeAgo":    func(t time.Time) string { return timeAgo(t, time.Now()) },
+}
+
+func join(sep string, elems []string) string { return strings.Join(elems, sep) }
+
+func formatTime(layout string, t time.Time) string { return t.Format(layout) }
+
+// badgeURL builds the URL of a shields.io badge image.
+func badgeURL(label, message, color string) string {
+	escape := strings.NewReplacer("-", "--", "_", "__", " ", "_").Replace
+	return fmt.Sprintf(
+		"https://img.shields.io/badge/%s-%s-%s",
+		url.PathEscape(escape(label)),
+		url.PathEscape(escape(message)),
+		url.PathEscape(color),
+	)
+}
+
+var (
+	mdCode   = regexp.MustCompile("`([^`]+)`")
+	mdLink   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdStrong = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdEm     = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+)
+
+// renderMarkdown renders the inline Markdown in text as HTML. Everything else
+// in text is escaped, so the result is safe to include in a page.
+func renderMarkdown(text string) template.HTML {
+	var (
+		b    strings.Builder
+		last int
+	)
+	for _, loc := range mdCode.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(renderMarkdownText(text[last:loc[0]]))
+		b.WriteString("<code>")
+		b.WriteString(html.EscapeString(text[loc[2]:loc[3]]))
+		b.WriteString("</code>")
+		last = loc[1]
+	}
+	b.WriteString(renderMarkdownText(text[last:]))
+	return template.HTML(b.String())
+}
+
+// renderMarkdownText renders the links and emphasis in text, which must not
+// contain code spans.
+func renderMarkdownText(text string) string {
+	text = html.EscapeString(text)
+	text = mdLink.ReplaceAllStringFunc(text, func(link string) string {
+		match := mdLink.FindStringSubmatch(link)
+		href := html.UnescapeString(match[2])
+		if !isSafeURL(href) {
+			return match[1]
+		}
+
+		// Escape asterisks, so that they aren't mistaken for emphasis.
+		href = strings.Replace(href, "*", "%2A", -1)
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(href),
+			match[1])
+	})
+	text = mdStrong.ReplaceAllString(text, "<strong>$1</strong>")
+	return mdEm.ReplaceAllString(text, "<em>$1</em>")
+}
+
+// isSafeURL returns true if u is a relative URL, or an absolute URL with a
+// scheme that is safe to link to.
+func isSafeURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+// timeAgo describes the time elapsed between t and now in rough,
+// human-readable terms.
+func timeAgo(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now.Sub(t)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		return plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		return plural(int(d/(365*24*time.Hour)), "year")
+	}
+}
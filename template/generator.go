@@ -1,14 +1,74 @@
 package template
 
 import (
+	"io"
 	"strings"
 
 	"go.stevenxie.me/vaingogh/pkg/urlutil"
+	"go.stevenxie.me/vaingogh/repo"
 )
 
-// An Generator can generate an vanity imports HTML page.
-type Generator interface {
-	GenerateHTML(prefix, address, repo string) (html string, err error)
+type (
+	// An Generator can generate an vanity imports HTML page.
+	Generator interface {
+		GenerateHTML(imp Import) (html string, err error)
+
+		// WriteHTML is like GenerateHTML, but writes the page to w instead of
+		// returning it. The page is written using a single call to w.Write,
+		// and only once it has been generated in full.
+		WriteHTML(w io.Writer, imp Import) error
+
+		// GenerateLandingHTML generates a human-readable landing page for a
+		// vanity import, for users that visit it in a browser.
+		GenerateLandingHTML(imp Import) (html string, err error)
+
+		// GenerateIndexHTML generates an index page that lists the modules
+		// served at baseURL.
+		GenerateIndexHTML(baseURL string, imps []Import) (html string, err error)
+
+		// GenerateNotFoundHTML generates a catch-all page for unknown paths on
+		// static hosts, which contains 'go-import' meta tags for every module
+		// in imps.
+		GenerateNotFoundHTML(baseURL string, imps []Import) (html string,
+			err error)
+
+		// GenerateErrorHTML generates an error page, for requests that could not
+		// be served.
+		GenerateErrorHTML(data ErrorData) (html string, err error)
+	}
+
+	// An Import describes a vanity import, for which a Generator can generate
+	// a page.
+	Import struct {
+		Prefix  string // the import path of the repo root
+		Address string // the requested import path
+		Repo    string // the full name of the repo
+
+		// Major is the major version suffix of the requested module (i.e. 'v2'),
+		// if any.
+		Major string
+
+		// Branch and Dir describe where the requested module is located within
+		// its repo. If Branch is empty, the repo's default branch is assumed.
+		Branch string
+		Dir    string
+
+		// Metadata contains metadata about the repo, if available.
+		Metadata *repo.Repo
+
+		// Versions describes the tagged versions of the requested module, if
+		// known.
+		Versions *repo.ModuleVersions
+	}
+)
+
+// ModulePath returns the module path of the import, including its major
+// version suffix.
+func (imp *Import) ModulePath() string {
+	if imp.Major == "" {
+		return imp.Prefix
+	}
+	return imp.Prefix + "/" + imp.Major
 }
 
 // WithSanitizer wraps a Generator with an input-sanitization layer.
@@ -20,10 +80,50 @@ type sanitizedGenerator struct {
 	Generator
 }
 
-func (sg sanitizedGenerator) GenerateHTML(prefix, address, repo string) (
-	html string, err error) {
-	prefix = urlutil.StripProtocol(prefix)
-	address = urlutil.StripProtocol(address)
-	repo = strings.Trim(repo, "/")
-	return sg.Generator.GenerateHTML(prefix, address, repo)
+func (sg sanitizedGenerator) GenerateHTML(imp Import) (html string,
+	err error) {
+	return sg.Generator.GenerateHTML(sanitizeImport(imp))
+}
+
+func (sg sanitizedGenerator) WriteHTML(w io.Writer, imp Import) error {
+	return sg.Generator.WriteHTML(w, sanitizeImport(imp))
+}
+
+func (sg sanitizedGenerator) GenerateLandingHTML(imp Import) (html string,
+	err error) {
+	return sg.Generator.GenerateLandingHTML(sanitizeImport(imp))
+}
+
+func (sg sanitizedGenerator) GenerateIndexHTML(
+	baseURL string,
+	imps []Import,
+) (html string, err error) {
+	baseURL, imps = sanitizeIndex(baseURL, imps)
+	return sg.Generator.GenerateIndexHTML(baseURL, imps)
+}
+
+func (sg sanitizedGenerator) GenerateNotFoundHTML(
+	baseURL string,
+	imps []Import,
+) (html string, err error) {
+	baseURL, imps = sanitizeIndex(baseURL, imps)
+	return sg.Generator.GenerateNotFoundHTML(baseURL, imps)
+}
+
+func sanitizeIndex(baseURL string, imps []Import) (string, []Import) {
+	baseURL = strings.Trim(urlutil.StripProtocol(baseURL), "/")
+	sanitized := make([]Import, len(imps))
+	for i, imp := range imps {
+		sanitized[i] = sanitizeImport(imp)
+	}
+	return baseURL, sanitized
+}
+
+func sanitizeImport(imp Import) Import {
+	imp.Prefix = strings.Trim(urlutil.StripProtocol(imp.Prefix), "/")
+	imp.Address = strings.Trim(urlutil.StripProtocol(imp.Address), "/")
+	imp.Repo = strings.Trim(imp.Repo, "/")
+	imp.Major = strings.Trim(imp.Major, "/")
+	imp.Dir = strings.Trim(imp.Dir, "/")
+	return imp
 }
@@ -2,25 +2,73 @@ package template
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/cockroachdb/errors"
 	"go.stevenxie.me/vaingogh/template"
 )
 
-const defaultBaseURL = "https://github.com"
+const (
+	defaultBaseURL = "https://github.com"
+	defaultBranch  = "master"
+	defaultDocsURL = "https://pkg.go.dev"
+)
 
 type (
 	// A Generator can generate an HTML page for a vanity import whose source
 	// originates from GitHub.
 	Generator struct {
 		templator *template.Templator
+		landing   *template.Templator
+		index     *template.Templator
+		notFound  *template.Templator
+		errorPage *template.Templator
 		baseURL   string
+
+		docsURL     string
+		metaRefresh bool
+		overrides   template.Overrides
+		site        template.Site
+		modProxy    template.ModProxy
 	}
 
 	// GeneratorConfig configures a Generator.
 	GeneratorConfig struct {
-		Template string
-		BaseURL  string // defaults to "https://github.com"
+		Template         string
+		LandingTemplate  string
+		IndexTemplate    string
+		NotFoundTemplate string
+		ErrorTemplate    string
+		BaseURL          string // defaults to "https://github.com"
+
+		// DocsURL is the base URL of the documentation site (defaults to
+		// "https://pkg.go.dev"). Set it to template.DocsDisabled to omit
+		// documentation links.
+		DocsURL string
+
+		// MetaRefresh determines whether or not 'go get' pages redirect visitors
+		// to the documentation site (defaults to true).
+		MetaRefresh bool
+
+		// Overrides overrides page generation for particular repos.
+		Overrides template.Overrides
+
+		// Site describes the site that pages are served on, for branding.
+		Site template.Site
+
+		// ModProxy describes a module proxy to advertise in 'go-import' meta
+		// tags. Its mode can be overridden for particular repos.
+		ModProxy template.ModProxy
+
+		// Templator, LandingTemplator, IndexTemplator, NotFoundTemplator, and
+		// ErrorTemplator are used in place of the corresponding templates if
+		// set, i.e. so that their templates can be reloaded.
+		Templator         *template.Templator
+		LandingTemplator  *template.Templator
+		IndexTemplator    *template.Templator
+		NotFoundTemplator *template.Templator
+		ErrorTemplator    *template.Templator
 	}
 )
 
@@ -29,47 +77,259 @@ var _ template.Generator = (*Generator)(nil)
 // NewGenerator creates a new Generator. It is safe for concurrent use.
 func NewGenerator(opts ...func(*GeneratorConfig)) (template.Generator, error) {
 	cfg := GeneratorConfig{
-		BaseURL: defaultBaseURL,
+		BaseURL:     defaultBaseURL,
+		DocsURL:     defaultDocsURL,
+		MetaRefresh: true,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	if err := cfg.ModProxy.Validate(); err != nil {
+		return nil, errors.Wrap(err, "github: invalid module proxy")
+	}
+	for name, override := range cfg.Overrides {
+		if err := override.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "github: invalid override for '%s'", name)
+		}
+		if (cfg.ModProxy.URL == "") && (override.ModProxy != "") &&
+			(override.ModProxy != template.ModProxyOff) {
+			return nil, errors.Newf("github: override for '%s' advertises a "+
+				"module proxy, but no module proxy URL is configured", name)
+		}
+	}
 
-	// Build templator.
-	templator, err := template.NewTemplator(
-		func(tc *template.TemplatorConfig) {
-			if cfg.Template != "" {
-				tc.Template = cfg.Template
-			}
-		},
-	)
+	// Build templators.
+	templator, err := buildTemplator(cfg.Templator, cfg.Template, "")
 	if err != nil {
 		return nil, errors.Wrap(err, "github: building templator")
 	}
+	landing, err := buildTemplator(
+		cfg.LandingTemplator,
+		cfg.LandingTemplate, template.DefaultLandingTemplate,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "github: building landing templator")
+	}
+	index, err := buildTemplator(
+		cfg.IndexTemplator,
+		cfg.IndexTemplate, template.DefaultIndexTemplate,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "github: building index templator")
+	}
+	notFound, err := buildTemplator(
+		cfg.NotFoundTemplator,
+		cfg.NotFoundTemplate, template.DefaultNotFoundTemplate,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "github: building not found templator")
+	}
+	errorPage, err := buildTemplator(
+		cfg.ErrorTemplator,
+		cfg.ErrorTemplate, template.DefaultErrorTemplate,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "github: building error templator")
+	}
 
 	return template.WithSanitizer(Generator{
 		templator: templator,
+		landing:   landing,
+		index:     index,
+		notFound:  notFound,
+		errorPage: errorPage,
 		baseURL:   cfg.BaseURL,
+
+		docsURL:     cfg.DocsURL,
+		metaRefresh: cfg.MetaRefresh,
+		overrides:   cfg.Overrides,
+		site:        cfg.Site,
+		modProxy:    cfg.ModProxy,
 	}), nil
 }
 
+// buildTemplator returns tplr if it is non-nil, and otherwise builds a
+// Templator from text (or def, if text is empty).
+func buildTemplator(
+	tplr *template.Templator,
+	text, def string,
+) (*template.Templator, error) {
+	if tplr != nil {
+		return tplr, nil
+	}
+	return template.NewTemplator(func(tc *template.TemplatorConfig) {
+		if text != "" {
+			tc.Template = text
+		} else if def != "" {
+			tc.Template = def
+		}
+	})
+}
+
 // GenerateHTML generates an HTML page for a vanity import.
-func (gen Generator) GenerateHTML(prefix, address, repo string) (html string,
+func (gen Generator) GenerateHTML(imp template.Import) (html string,
+	err error) {
+	return gen.goGetTemplator(&imp).TemplateHTML(gen.templatorData(&imp))
+}
+
+// WriteHTML writes an HTML page for a vanity import to w.
+func (gen Generator) WriteHTML(w io.Writer, imp template.Import) error {
+	return gen.goGetTemplator(&imp).WriteHTML(w, gen.templatorData(&imp))
+}
+
+// GenerateLandingHTML generates a landing page for a vanity import.
+func (gen Generator) GenerateLandingHTML(imp template.Import) (html string,
+	err error) {
+	tplr := gen.landing
+	if override := gen.overrides.Lookup(imp.Repo); override.LandingTemplator !=
+		nil {
+		tplr = override.LandingTemplator
+	}
+	return tplr.TemplateHTML(gen.templatorData(&imp))
+}
+
+func (gen Generator) goGetTemplator(imp *template.Import) *template.Templator {
+	if override := gen.overrides.Lookup(imp.Repo); override.Templator != nil {
+		return override.Templator
+	}
+	return gen.templator
+}
+
+// GenerateIndexHTML generates an index page for a set of vanity imports.
+func (gen Generator) GenerateIndexHTML(
+	baseURL string,
+	imps []template.Import,
+) (html string, err error) {
+	return gen.index.TemplateIndexHTML(gen.indexData(baseURL, imps))
+}
+
+// GenerateNotFoundHTML generates a catch-all page for a set of vanity
+// imports.
+func (gen Generator) GenerateNotFoundHTML(
+	baseURL string,
+	imps []template.Import,
+) (html string, err error) {
+	return gen.notFound.TemplateIndexHTML(gen.indexData(baseURL, imps))
+}
+
+func (gen Generator) indexData(
+	baseURL string,
+	imps []template.Import,
+) template.IndexData {
+	data := template.IndexData{
+		BaseURL: baseURL,
+		Modules: make([]template.TemplatorData, len(imps)),
+		Site:    gen.site,
+	}
+	for i := range imps {
+		data.Modules[i] = gen.templatorData(&imps[i])
+	}
+	return data
+}
+
+// GenerateErrorHTML generates an error page.
+func (gen Generator) GenerateErrorHTML(data template.ErrorData) (html string,
 	err error) {
-	sourceURL := fmt.Sprintf("%s/%s", gen.baseURL, repo)
+	data.Site = gen.site
+	return gen.errorPage.TemplateErrorHTML(data)
+}
+
+func (gen Generator) templatorData(imp *template.Import) template.TemplatorData {
+	var (
+		override  = gen.overrides.Lookup(imp.Repo)
+		sourceURL = fmt.Sprintf("%s/%s", gen.baseURL, imp.Repo)
+		branch    = imp.Branch
+		dir       string
+	)
+	if (branch == "") && (imp.Metadata != nil) {
+		branch = imp.Metadata.DefaultBranch
+	}
+	if branch == "" {
+		branch = defaultBranch
+	}
+	if imp.Dir != "" {
+		dir = "/" + imp.Dir
+	}
 
-	// Fill out template data.
+	// Fill out template data, merging the repo's override over the defaults.
+	if override.SourceURL != "" {
+		sourceURL = strings.TrimSuffix(override.SourceURL, "/")
+	}
 	data := template.TemplatorData{
-		Prefix:        prefix,
-		Address:       address,
+		Prefix:        imp.Prefix,
+		Address:       imp.Address,
+		ModulePath:    imp.ModulePath(),
 		VCSType:       "git",
 		ImportURL:     sourceURL,
 		SourceURL:     sourceURL,
-		SourceTreeURL: fmt.Sprintf("%s/tree/master{/dir}", sourceURL),
-		SourceBlobURL: fmt.Sprintf("%s/blob/master{/dir}/{file}#L{line}",
-			sourceURL),
+		SourceTreeURL: fmt.Sprintf("%s/tree/%s%s{/dir}", sourceURL, branch, dir),
+		SourceBlobURL: fmt.Sprintf("%s/blob/%s%s{/dir}/{file}#L{line}",
+			sourceURL, branch, dir),
+		MetaRefresh: gen.metaRefresh,
+		Site:        gen.site,
+	}
+	if override.VCSType != "" {
+		data.VCSType = override.VCSType
+	}
+	if override.ImportURL != "" {
+		data.ImportURL = override.ImportURL
+	}
+	if override.SourceTreeURL != "" {
+		data.SourceTreeURL = override.SourceTreeURL
+	}
+	if override.SourceBlobURL != "" {
+		data.SourceBlobURL = override.SourceBlobURL
 	}
 
-	// Execute template using data.
-	return gen.templator.TemplateHTML(data)
+	// Advertise module proxy.
+	mode := gen.modProxy.Mode
+	if override.ModProxy != "" {
+		mode = override.ModProxy
+	}
+	switch mode {
+	case template.ModProxyAlongside:
+		if data.VCSType != "mod" {
+			data.ProxyURL = gen.modProxy.URL
+		}
+	case template.ModProxyInstead:
+		data.VCSType = "mod"
+		data.ImportURL = gen.modProxy.URL
+
+		// Hide the repo, unless its source URL was overridden explicitly.
+		if override.SourceURL == "" {
+			data.SourceURL, data.SourceTreeURL, data.SourceBlobURL = "", "", ""
+		}
+	}
+
+	// Build docs URL.
+	docsURL := gen.docsURL
+	if override.DocsURL != "" {
+		docsURL = override.DocsURL
+	}
+	data.DocsURL = template.DocsURL(docsURL, imp.Address)
+
+	if meta := imp.Metadata; meta != nil {
+		data.Description = meta.Description
+		data.LatestVersion = meta.LatestTag
+		data.Topics = meta.Topics
+		data.Stars = meta.Stars
+		data.UpdatedAt = meta.UpdatedAt
+		if versions := imp.Versions; (versions != nil) &&
+			(versions.Latest != "") {
+			data.LatestVersion = versions.Latest
+			data.Prerelease = versions.Prerelease
+		}
+		if license := meta.License; license != nil {
+			data.License = license.Name
+			if (license.SPDXID != "") && (license.SPDXID != "NOASSERTION") {
+				data.LicenseURL = "https://spdx.org/licenses/" + license.SPDXID +
+					".html"
+			}
+		}
+	}
+	if versions := imp.Versions; versions != nil {
+		data.Versions = versions.Versions
+		data.Retracted = versions.Retracted
+	}
+	return data
 }
@@ -0,0 +1,40 @@
+package template
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"go.stevenxie.me/vaingogh/template"
+)
+
+func BenchmarkGenerator(b *testing.B) {
+	gen, err := NewGenerator()
+	if err != nil {
+		b.Fatal(err)
+	}
+	imp := template.Import{
+		Prefix:  "go.example.com/repo",
+		Address: "go.example.com/repo/pkg",
+		Repo:    "user/repo",
+	}
+
+	b.Run("GenerateHTML", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := gen.GenerateHTML(imp); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+
+	b.Run("WriteHTML", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := gen.WriteHTML(ioutil.Discard, imp); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package template
+
+// DefaultIndexTemplate is the default template for the module index page,
+// which lists all served modules at the vanity root.
+const DefaultIndexTemplate = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1"/>
+    <title>{{ with .Site.Name }}{{ . }}{{ else }}{{ .BaseURL }}{{ end }}</title>
+    <style>
+      body {
+        max-width: 48rem;
+        margin: 3rem auto;
+        padding: 0 1rem;
+        font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica,
+          Arial, sans-serif;
+        line-height: 1.5;
+        color: #24292e;
+      }
+      h1 { font-size: 1.5rem; }
+      h1 img { height: 1.5em; margin-right: 0.5rem; vertical-align: middle; }
+      input {
+        width: 100%;
+        box-sizing: border-box;
+        padding: 0.5rem 0.75rem;
+        font-size: 1rem;
+        border: 1px solid #d1d5da;
+        border-radius: 0.25rem;
+      }
+      ul.modules { padding: 0; list-style: none; }
+      ul.modules > li { padding: 1rem 0; border-bottom: 1px solid #eaecef; }
+      .path { font-weight: 600; word-break: break-all; }
+      .description { margin: 0.25rem 0; color: #586069; }
+      .meta { font-size: 0.875rem; color: #6a737d; }
+      .meta a { margin-right: 1rem; }
+      a { color: #0366d6; }
+    </style>
+  </head>
+  <body>
+    <h1>
+      {{- with .Site.LogoURL }}<img src="{{ . }}" alt="">{{ end -}}
+      {{ with .Site.Name }}{{ . }}{{ else }}{{ .BaseURL }}{{ end -}}
+    </h1>
+    <input id="search" type="search" placeholder="Search modules..." autofocus>
+    <ul class="modules">
+      {{- range .Modules }}
+      <li data-search="{{ .ModulePath }} {{ .Description }} {{ join " " .Topics }}">
+        <a class="path" href="//{{ .ModulePath }}">{{ .ModulePath }}</a>
+        {{- with .Description }}
+        <p class="description">{{ markdown . }}</p>
+        {{- end }}
+        <div class="meta">
+          {{- with .LatestVersion }}
+          <span>{{ . }}</span>
+          {{- end }}
+          {{- with .SourceURL }}
+          <a href="{{ . }}">Source</a>
+          {{- end }}
+          {{- with .DocsURL }}
+          <a href="{{ . }}">Documentation</a>
+          {{- end }}
+          {{- if not .UpdatedAt.IsZero }}
+          Updated {{ .UpdatedAt | formatTime "Jan 2, 2006" }}
+          {{- end }}
+        </div>
+      </li>
+      {{- else }}
+      <li>No modules found.</li>
+      {{- end }}
+    </ul>
+    <script>
+      (function () {
+        var search = document.getElementById("search");
+        var items = document.querySelectorAll("ul.modules > li[data-search]");
+        search.addEventListener("input", function () {
+          var query = search.value.trim().toLowerCase();
+          for (var i = 0; i < items.length; i++) {
+            var text = items[i].getAttribute("data-search").toLowerCase();
+            items[i].style.display = text.indexOf(query) > -1 ? "" : "none";
+          }
+        });
+      })();
+    </script>
+  </body>
+</html>
+`
@@ -0,0 +1,78 @@
+package template
+
+// DefaultLandingTemplate is the default template for module landing pages,
+// which are shown to users who visit a module's import path in a browser.
+const DefaultLandingTemplate = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1"/>
+    <meta name="go-import" content="{{ .Prefix }} {{ .VCSType }} {{ .ImportURL }}">
+    {{- with .ProxyURL }}
+    <meta name="go-import" content="{{ $.Prefix }} mod {{ . }}">
+    {{- end }}
+    {{- if .SourceURL }}
+    <meta name="go-source" content="{{ .ModulePath }} {{ .SourceURL }} {{ .SourceTreeURL }} {{ .SourceBlobURL }}">
+    {{- end }}
+    <title>{{ .ModulePath }}{{ with .Site.Name }} - {{ . }}{{ end }}</title>
+    <style>
+      body {
+        max-width: 42rem;
+        margin: 3rem auto;
+        padding: 0 1rem;
+        font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica,
+          Arial, sans-serif;
+        line-height: 1.5;
+        color: #24292e;
+      }
+      h1 { font-size: 1.5rem; word-break: break-all; }
+      .description, .retracted { color: #586069; }
+      .topics { padding: 0; list-style: none; }
+      .topics li {
+        display: inline-block;
+        margin: 0 0.25rem 0.25rem 0;
+        padding: 0 0.5rem;
+        font-size: 0.75rem;
+        border-radius: 1rem;
+        background: #f1f8ff;
+      }
+      .version {
+        font-size: 0.875rem;
+        padding: 0.125rem 0.5rem;
+        border-radius: 1rem;
+        background: #e1f5fe;
+        vertical-align: middle;
+      }
+      pre {
+        padding: 0.75rem 1rem;
+        overflow-x: auto;
+        border-radius: 0.25rem;
+        background: #f6f8fa;
+      }
+      ul.links { padding: 0; list-style: none; }
+      ul.links li { display: inline; margin-right: 1rem; }
+      a { color: #0366d6; }
+    </style>
+  </head>
+  <body>
+    <h1>
+      {{ .ModulePath }}
+      {{ with .LatestVersion }}<span class="version">{{ . }}{{ if $.Prerelease }} (prerelease){{ end }}</span>{{ end }}
+    </h1>
+    {{ with .Description }}<p class="description">{{ markdown . }}</p>{{ end }}
+    {{ with .Topics }}<ul class="topics">{{ range . }}<li>{{ . }}</li>{{ end }}</ul>{{ end }}
+    <h2>Install</h2>
+    <pre><code>go get {{ .Address }}</code></pre>
+    <ul class="links">
+      {{ with .SourceURL }}<li><a href="{{ . }}">Source</a></li>{{ end }}
+      {{ with .DocsURL }}<li><a href="{{ . }}">Documentation</a></li>{{ end }}
+      {{ if .Stars }}<li>&#9733; {{ .Stars }}</li>{{ end }}
+      {{ if .License }}<li>{{ if .LicenseURL }}<a href="{{ .LicenseURL }}">{{ .License }}</a>{{ else }}{{ .License }}{{ end }}</li>{{ end }}
+    </ul>
+    {{ with .Retracted }}
+    <h2>Retracted Versions</h2>
+    <p class="retracted">{{ range $i, $v := . }}{{ if $i }}, {{ end }}<code>{{ $v }}</code>{{ end }}</p>
+    {{ end }}
+  </body>
+</html>
+`
@@ -0,0 +1,64 @@
+package template
+
+import (
+	"net/url"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Modes in which a module proxy can be advertised in 'go-import' meta tags,
+// using the 'mod' VCS type.
+const (
+	// ModProxyOff doesn't advertise a module proxy.
+	ModProxyOff = "off"
+
+	// ModProxyAlongside advertises a module proxy alongside the repo, so that
+	// the 'go' command uses the proxy in module mode, and the repo otherwise.
+	ModProxyAlongside = "alongside"
+
+	// ModProxyInstead advertises a module proxy instead of the repo, so that
+	// the repo's URLs aren't exposed.
+	ModProxyInstead = "instead"
+)
+
+// A ModProxy describes a module proxy that is advertised in 'go-import' meta
+// tags.
+type ModProxy struct {
+	// URL is the base URL of the proxy, as would be used in GOPROXY.
+	URL string
+
+	// Mode is one of ModProxyOff, ModProxyAlongside, or ModProxyInstead. If
+	// empty, ModProxyOff is assumed.
+	Mode string
+}
+
+// Validate returns an error if the ModProxy is not valid.
+func (mp *ModProxy) Validate() error {
+	if err := validateModProxyMode(mp.Mode); err != nil {
+		return err
+	}
+	if mp.URL == "" {
+		if (mp.Mode != "") && (mp.Mode != ModProxyOff) {
+			return errors.New("template: module proxy URL is required")
+		}
+		return nil
+	}
+	parsed, err := url.Parse(mp.URL)
+	if err != nil {
+		return errors.Wrap(err, "template: invalid module proxy URL")
+	}
+	if (parsed.Scheme == "") || (parsed.Host == "") {
+		return errors.Newf("template: module proxy URL '%s' must be absolute",
+			mp.URL)
+	}
+	return nil
+}
+
+func validateModProxyMode(mode string) error {
+	switch mode {
+	case "", ModProxyOff, ModProxyAlongside, ModProxyInstead:
+		return nil
+	default:
+		return errors.Newf("template: unknown module proxy mode '%s'", mode)
+	}
+}
@@ -0,0 +1,41 @@
+package template
+
+// DefaultNotFoundTemplate is the default template for catch-all 'not found'
+// pages, which are used when serving vanity imports from static hosts.
+//
+// Since a static host serves the same page for every unknown path, it
+// contains 'go-import' meta tags for all modules; the 'go' command uses the
+// one whose prefix matches the requested import path.
+const DefaultNotFoundTemplate = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1"/>
+    {{- range $mod := .Modules }}
+    <meta name="go-import" content="{{ .Prefix }} {{ .VCSType }} {{ .ImportURL }}">
+    {{- with .ProxyURL }}
+    <meta name="go-import" content="{{ $mod.Prefix }} mod {{ . }}">
+    {{- end }}
+    {{- end }}
+    <title>404 Not Found{{ with .Site.Name }} - {{ . }}{{ end }}</title>
+    <style>
+      body {
+        max-width: 42rem;
+        margin: 3rem auto;
+        padding: 0 1rem;
+        font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica,
+          Arial, sans-serif;
+        line-height: 1.5;
+        color: #24292e;
+      }
+      h1 { font-size: 1.5rem; }
+      .status { color: #586069; }
+      a { color: #0366d6; }
+    </style>
+  </head>
+  <body>
+    <h1><span class="status">404</span> Not Found</h1>
+    <p>See <a href="//{{ .BaseURL }}">{{ .BaseURL }}</a> for a list of modules.</p>
+  </body>
+</html>
+`
@@ -0,0 +1,117 @@
+package template
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DocsDisabled can be used in place of a docs URL to indicate that a module
+// has no documentation site.
+const DocsDisabled = "none"
+
+// An Override overrides how a Generator generates pages for a particular
+// repo. Empty fields are not overridden.
+type Override struct {
+	// DocsURL is the base URL of the documentation site for the repo's
+	// modules, or DocsDisabled.
+	DocsURL string
+
+	// VCSType and ImportURL override the version control system and repo URL
+	// in 'go-import' meta tags, i.e. to point at an internal mirror.
+	VCSType   string
+	ImportURL string
+
+	// SourceURL, SourceTreeURL, and SourceBlobURL override the URLs in
+	// 'go-source' meta tags. SourceTreeURL and SourceBlobURL are used as-is,
+	// and so should contain the '{/dir}', '{file}', and '{line}' placeholders;
+	// if they aren't set, they are derived from SourceURL.
+	SourceURL     string
+	SourceTreeURL string
+	SourceBlobURL string
+
+	// ModProxy overrides the mode in which a module proxy is advertised for
+	// the repo's modules (see ModProxy).
+	ModProxy string
+
+	// Templator and LandingTemplator override the templators used for the
+	// repo's 'go get' pages and landing pages.
+	Templator        *Templator
+	LandingTemplator *Templator
+}
+
+// VCSTypes are the version control systems that can be used in 'go-import'
+// meta tags.
+var VCSTypes = []string{"git", "hg", "svn", "bzr", "fossil", "mod"}
+
+// Validate returns an error if the Override is not valid.
+func (o *Override) Validate() error {
+	if o.VCSType != "" {
+		var known bool
+		for _, vcs := range VCSTypes {
+			if o.VCSType == vcs {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return errors.Newf("template: unknown VCS type '%s'", o.VCSType)
+		}
+	}
+	if err := validateModProxyMode(o.ModProxy); err != nil {
+		return err
+	}
+	docsURL := o.DocsURL
+	if docsURL == DocsDisabled {
+		docsURL = ""
+	}
+	urls := []struct{ name, value string }{
+		{"docs URL", docsURL},
+		{"import URL", o.ImportURL},
+		{"source URL", o.SourceURL},
+		{"source tree URL", o.SourceTreeURL},
+		{"source blob URL", o.SourceBlobURL},
+	}
+	for _, u := range urls {
+		if u.value == "" {
+			continue
+		}
+		parsed, err := url.Parse(u.value)
+		if err != nil {
+			return errors.Wrapf(err, "template: invalid %s", u.name)
+		}
+		if (parsed.Scheme == "") || (parsed.Host == "") {
+			return errors.Newf("template: %s '%s' must be absolute", u.name,
+				u.value)
+		}
+	}
+	return nil
+}
+
+// Overrides maps full repo names to Overrides.
+type Overrides map[string]Override
+
+// Lookup returns the Override for the repo named name. The lookup is
+// case-insensitive.
+func (o Overrides) Lookup(name string) Override {
+	if ov, ok := o[name]; ok {
+		return ov
+	}
+	for key, ov := range o {
+		if strings.EqualFold(key, name) {
+			return ov
+		}
+	}
+	return Override{}
+}
+
+// DocsURL builds the URL for the documentation page of importPath, using the
+// documentation site at baseURL. It returns an empty string if baseURL is
+// empty or DocsDisabled.
+func DocsURL(baseURL, importPath string) string {
+	if (baseURL == "") || (baseURL == DocsDisabled) {
+		return ""
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + importPath
+}
@@ -0,0 +1,197 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"go.stevenxie.me/api/pkg/zero"
+)
+
+// Sample data for the built-in page types, for use as TemplatorConfig.Sample.
+var (
+	SampleData = TemplatorData{
+		Prefix:        "example.com/mod",
+		Address:       "example.com/mod/pkg",
+		ModulePath:    "example.com/mod",
+		VCSType:       "git",
+		ImportURL:     "https://github.com/example/mod",
+		SourceURL:     "https://github.com/example/mod",
+		SourceTreeURL: "https://github.com/example/mod/tree/master{/dir}",
+		SourceBlobURL: "https://github.com/example/mod/blob/master{/dir}/{file}#L{line}",
+		DocsURL:       "https://pkg.go.dev/example.com/mod/pkg",
+		Description:   "An example module.",
+		LatestVersion: "v1.1.0",
+		License:       "MIT License",
+		LicenseURL:    "https://spdx.org/licenses/MIT.html",
+		Topics:        []string{"go", "example"},
+		Stars:         42,
+		UpdatedAt:     time.Date(2019, time.October, 1, 0, 0, 0, 0, time.UTC),
+		Versions:      []string{"v1.0.0", "v1.1.0"},
+		Site:          sampleSite,
+	}
+	SampleIndexData = IndexData{
+		BaseURL: "example.com",
+		Modules: []TemplatorData{SampleData},
+		Site:    sampleSite,
+	}
+	SampleErrorData = ErrorData{
+		Status:     500,
+		StatusText: "Internal Server Error",
+		Message:    "An internal error occurred.",
+		RequestID:  "example",
+		Site:       sampleSite,
+	}
+
+	sampleSite = Site{
+		Name:    "Example",
+		LogoURL: "https://example.com/logo.png",
+		BaseURL: "example.com",
+	}
+)
+
+// LoadTemplator creates a Templator from the template file at path, which is
+// test-executed with sample (if non-nil) to validate it.
+func LoadTemplator(path string, sample interface{}) (*Templator, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "vanity: reading template file")
+	}
+	tplr, err := NewTemplator(func(tc *TemplatorConfig) {
+		tc.Template = string(text)
+		tc.Sample = sample
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "vanity: loading template file '%s'", path)
+	}
+	return tplr, nil
+}
+
+// NewReloader creates a new Reloader, which checks its files for changes
+// every interval.
+func NewReloader(
+	interval time.Duration,
+	opts ...func(*ReloaderConfig),
+) *Reloader {
+	cfg := ReloaderConfig{
+		Logger: zero.Logger(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	rl := &Reloader{
+		log:      cfg.Logger,
+		onReload: cfg.OnReload,
+		stop:     make(chan zero.Struct),
+	}
+	go rl.run(interval)
+	return rl
+}
+
+type (
+	// A Reloader watches template files, and reloads their Templators
+	// whenever they change.
+	//
+	// If a changed template fails to load, its Templator keeps its previous
+	// template. It is safe for concurrent use.
+	Reloader struct {
+		log      logrus.FieldLogger
+		onReload func()
+		stop     chan zero.Struct
+		once     sync.Once
+
+		mux   sync.Mutex
+		files []*watchedFile
+	}
+
+	// A ReloaderConfig configures a Reloader.
+	ReloaderConfig struct {
+		Logger logrus.FieldLogger
+
+		// OnReload, if set, is called after a template is reloaded successfully.
+		OnReload func()
+	}
+
+	watchedFile struct {
+		path      string
+		templator *Templator
+		modTime   time.Time
+		size      int64
+	}
+)
+
+// Watch reloads tplr from the template file at path whenever it changes.
+func (rl *Reloader) Watch(path string, tplr *Templator) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrap(err, "vanity: reading template file info")
+	}
+
+	rl.mux.Lock()
+	defer rl.mux.Unlock()
+	rl.files = append(rl.files, &watchedFile{
+		path:      path,
+		templator: tplr,
+		modTime:   info.ModTime(),
+		size:      info.Size(),
+	})
+	return nil
+}
+
+// Stop stops watching files for changes.
+func (rl *Reloader) Stop() {
+	rl.once.Do(func() { close(rl.stop) })
+}
+
+func (rl *Reloader) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-ticker.C:
+			rl.check()
+		}
+	}
+}
+
+// check reloads the templates of files that have changed since they were
+// last checked.
+func (rl *Reloader) check() {
+	rl.mux.Lock()
+	defer rl.mux.Unlock()
+
+	for _, file := range rl.files {
+		log := rl.log.WithField("path", file.path)
+		info, err := os.Stat(file.path)
+		if err != nil {
+			log.WithError(err).Error("Failed to read template file info.")
+			continue
+		}
+		if info.ModTime().Equal(file.modTime) && (info.Size() == file.size) {
+			continue
+		}
+
+		// Record the file's new state regardless of whether or not it loads,
+		// so that a broken template is only reported once.
+		file.modTime, file.size = info.ModTime(), info.Size()
+		text, err := ioutil.ReadFile(file.path)
+		if err != nil {
+			log.WithError(err).Error("Failed to read template file.")
+			continue
+		}
+		if err = file.templator.Parse(string(text)); err != nil {
+			log.WithError(err).
+				Error("Failed to reload template; keeping previous template.")
+			continue
+		}
+		log.Info("Reloaded template.")
+		if rl.onReload != nil {
+			rl.onReload()
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package template
 
 import (
+	"bytes"
 	"html/template"
-	"strings"
+	"io"
+	"io/ioutil"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cockroachdb/errors"
 )
@@ -17,55 +21,198 @@ func NewTemplator(opts ...func(*TemplatorConfig)) (*Templator, error) {
 		opt(&cfg)
 	}
 
-	// Parse template.
-	tpl, err := template.New("html").Parse(cfg.Template)
-	if err != nil {
-		return nil, errors.Wrap(err, "vanity: parsing HTML template")
+	tplr := &Templator{sample: cfg.Sample}
+	if err := tplr.Parse(cfg.Template); err != nil {
+		return nil, err
 	}
-
-	return &Templator{tpl: tpl}, nil
+	return tplr, nil
 }
 
 type (
 	// A Templator generalizes the process for generating vanity import pages
 	// from a template.
 	//
-	// It is safe for concurrent use.
+	// It is safe for concurrent use; renders don't block one another, and
+	// its template can be replaced while it is in use.
 	Templator struct {
-		mux     sync.Mutex
-		tpl     *template.Template
-		builder strings.Builder
+		tpl    atomic.Value // *template.Template
+		sample interface{}
 	}
 
 	// TemplatorConfig configures a Templator.
 	TemplatorConfig struct {
 		Template string // defaults to `defaultRawTpl`
+
+		// Sample is example data that templates are test-executed with before
+		// they are used, so that errors like references to nonexistent fields
+		// are caught early. If nil, templates are only parsed.
+		Sample interface{}
 	}
 
 	// TemplatorData contains fields that can be used to fill out the
 	// vanity mport page template.
 	TemplatorData struct {
-		Prefix        string
-		Address       string
-		VCSType       string
-		ImportURL     string
+		Prefix     string
+		Address    string
+		ModulePath string
+		VCSType    string
+		ImportURL  string
+
+		// ProxyURL is the URL of a module proxy that is advertised alongside
+		// the repo (using the 'mod' VCS type), if any.
+		ProxyURL string
+
+		// SourceURL, SourceTreeURL, and SourceBlobURL are empty if the repo
+		// shouldn't be exposed.
 		SourceURL     string
 		SourceTreeURL string
 		SourceBlobURL string
+
+		// DocsURL is the URL of the import's documentation page; it is empty if
+		// the module has no documentation site. If MetaRefresh is true, pages
+		// should redirect to DocsURL.
+		DocsURL     string
+		MetaRefresh bool
+
+		// Module metadata, used in landing pages.
+		Description   string
+		LatestVersion string
+		License       string
+		LicenseURL    string
+		Topics        []string
+		Stars         int
+		UpdatedAt     time.Time
+
+		// Prerelease is true if LatestVersion is a prerelease version.
+		Prerelease bool
+
+		// Versions are the module's known versions, in ascending order, and
+		// Retracted are those that have been retracted.
+		Versions  []string
+		Retracted []string
+
+		Site Site
+	}
+
+	// IndexData contains fields that can be used to fill out the module index
+	// page template.
+	IndexData struct {
+		BaseURL string
+		Modules []TemplatorData
+		Site    Site
+	}
+
+	// ErrorData contains fields that can be used to fill out the error page
+	// template.
+	ErrorData struct {
+		Status     int    // the HTTP status code of the response
+		StatusText string // the standard text for Status
+		Message    string // a description of the error, safe to show to users
+
+		// RequestID identifies the failed request, so that it can be found in
+		// the server logs.
+		RequestID string
+
+		Site Site
+	}
+
+	// Site describes the site that pages are served on, for branding.
+	Site struct {
+		Name    string // a display name for the site; may be empty
+		LogoURL string // the URL of the site's logo; may be empty
+		BaseURL string // the vanity import base URL, i.e. 'go.example.com'
 	}
 )
 
+// Parse parses text as the Templator's new template.
+//
+// The new template replaces the current one atomically, and only if it parses
+// (and executes with the Templator's sample data) successfully; otherwise,
+// the current template is kept.
+func (tplr *Templator) Parse(text string) error {
+	tpl, err := template.New("html").Funcs(FuncMap).Parse(text)
+	if err != nil {
+		return errors.Wrap(err, "vanity: parsing HTML template")
+	}
+	if tplr.sample != nil {
+		if err = tpl.Execute(ioutil.Discard, tplr.sample); err != nil {
+			return errors.Wrap(err, "vanity: executing HTML template")
+		}
+	}
+	tplr.tpl.Store(tpl)
+	return nil
+}
+
+func (tplr *Templator) template() *template.Template {
+	return tplr.tpl.Load().(*template.Template)
+}
+
 // TemplateHTML generates an HTML page for a vanity import.
 func (tplr *Templator) TemplateHTML(data TemplatorData) (html string,
 	err error) {
-	// Protect against concurrent access.
-	tplr.mux.Lock()
-	defer tplr.mux.Unlock()
+	return tplr.Execute(&data)
+}
+
+// TemplateIndexHTML generates an HTML index page for a set of modules.
+func (tplr *Templator) TemplateIndexHTML(data IndexData) (html string,
+	err error) {
+	return tplr.Execute(&data)
+}
+
+// TemplateErrorHTML generates an HTML error page.
+func (tplr *Templator) TemplateErrorHTML(data ErrorData) (html string,
+	err error) {
+	return tplr.Execute(&data)
+}
+
+// WriteHTML writes an HTML page for a vanity import to w.
+func (tplr *Templator) WriteHTML(w io.Writer, data TemplatorData) error {
+	return tplr.ExecuteTo(w, &data)
+}
 
-	// Template HTML using data.
-	defer tplr.builder.Reset()
-	if err = tplr.tpl.Execute(&tplr.builder, &data); err != nil {
+// Execute executes the Templator's template using data, and returns the
+// resulting HTML.
+func (tplr *Templator) Execute(data interface{}) (html string, err error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err = tplr.template().Execute(buf, data); err != nil {
 		return "", err
 	}
-	return tplr.builder.String(), nil
+	return buf.String(), nil
+}
+
+// ExecuteTo executes the Templator's template using data, and writes the
+// resulting HTML to w.
+//
+// The HTML is only written once the template has been executed successfully,
+// using a single call to w.Write, so that w never receives a partial page.
+func (tplr *Templator) ExecuteTo(w io.Writer, data interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := tplr.template().Execute(buf, data); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// maxPooledBufferSize is the capacity above which buffers are discarded
+// instead of being returned to bufferPool, so that the pool doesn't retain
+// unusually large pages.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buf)
+	}
 }
@@ -0,0 +1,41 @@
+package template
+
+import "testing"
+
+// BenchmarkTemplatorTemplateHTML measures page rendering under parallel load.
+// It only uses the Templator's exported API, so that it can also be run
+// against earlier versions of the Templator for comparison.
+func BenchmarkTemplatorTemplateHTML(b *testing.B) {
+	// Use a fixed template, since the default template differs between
+	// versions.
+	tplr, err := NewTemplator(func(cfg *TemplatorConfig) {
+		cfg.Template = `<!DOCTYPE html>
+<html>
+<head>
+<meta name="go-import" content="{{.Prefix}} {{.VCSType}} {{.ImportURL}}">
+<meta name="go-source" content="{{.Prefix}} {{.SourceURL}}">
+</head>
+<body>go get {{.Address}}</body>
+</html>
+`
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := TemplatorData{
+		Prefix:    "go.example.com/repo",
+		Address:   "go.example.com/repo/pkg",
+		VCSType:   "git",
+		ImportURL: "https://github.com/user/repo",
+		SourceURL: "https://github.com/user/repo",
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := tplr.TemplateHTML(data); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}